@@ -0,0 +1,673 @@
+// Package config loads, validates and encodes meteor-shower configuration
+// files.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	LoadTest LoadTestConfig `yaml:"loadtest" description:"Load test settings"`
+
+	// Path is the config file the values were loaded from, empty when only
+	// defaults are in effect.
+	Path string `yaml:"-"`
+
+	// Secrets collects values resolved from secret references while loading.
+	Secrets Secrets `yaml:"-"`
+
+	// EnvOverrides are the METEOR_SHOWER_* environment variables that
+	// override values of the config file, in the order they were applied.
+	EnvOverrides []string `yaml:"-"`
+}
+
+const envPrefix = "METEOR_SHOWER_"
+
+// Test modes.
+const (
+	ModeLoad   = "load"
+	ModeReplay = "replay"
+	ModeSearch = "search"
+)
+
+// Capacity search strategies.
+const (
+	SearchRamp   = "ramp"
+	SearchBinary = "binary"
+)
+
+// Authentication types.
+const (
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+	AuthAPIKey = "api_key"
+	AuthOAuth2 = "oauth2"
+)
+
+// API key locations.
+const (
+	APIKeyHeader = "header"
+	APIKeyQuery  = "query"
+)
+
+// Cookie jar resets.
+const (
+	CookieResetNever     = "never"
+	CookieResetIteration = "iteration"
+)
+
+// Feeder orders.
+const (
+	FeederSequential = "sequential"
+	FeederRandom     = "random"
+)
+
+// Endpoint types.
+const (
+	EndpointHTTP      = "http"
+	EndpointGRPC      = "grpc"
+	EndpointWebSocket = "websocket"
+	EndpointSSE       = "sse"
+	EndpointGraphQL   = "graphql"
+	EndpointDNS       = "dns"
+	EndpointTCP       = "tcp"
+	EndpointUDP       = "udp"
+)
+
+// Replay timings.
+const (
+	TimingOriginal = "original"
+	TimingRPS      = "rps"
+)
+
+type LoadTestConfig struct {
+	Mode        string           `yaml:"mode,omitempty" description:"Test mode: load sends weighted requests to endpoints, replay reissues requests from a log, search looks for the highest RPS that meets the SLO" jsonschema:"enum=load|replay|search,default=load"`
+	Domain      string           `yaml:"domain" description:"Target domain, e.g. http://localhost:8080" jsonschema:"format=uri"`
+	Endpoints   []Endpoint       `yaml:"endpoints" description:"Endpoints with weights" jsonschema:"minItems=1"`
+	RPS         int              `yaml:"rps" description:"Requests per second" jsonschema:"exclusiveMinimum=0"`
+	Concurrency int              `yaml:"concurrency" description:"Number of concurrent clients" jsonschema:"exclusiveMinimum=0"`
+	Duration    int              `yaml:"duration" description:"Test duration in seconds" jsonschema:"exclusiveMinimum=0"`
+	Output      string           `yaml:"output" description:"Output format" jsonschema:"enum=html|json"`
+	Replay      ReplayConfig     `yaml:"replay,omitempty" description:"Replay mode settings"`
+	Search      SearchConfig     `yaml:"search,omitempty" description:"Search mode settings"`
+	Feeders     []Feeder         `yaml:"feeders,omitempty" description:"CSV files whose rows request templates read with the feeder function"`
+	Cookies     CookieConfig     `yaml:"cookies,omitempty" description:"Cookie handling of HTTP requests"`
+	Auth        AuthConfig       `yaml:"auth,omitempty" description:"Credentials added to every http, graphql, sse, websocket and grpc request"`
+	Checkpoint  CheckpointConfig `yaml:"checkpoint,omitempty" description:"Interim reports of long load mode runs"`
+	Stop        StopConfig       `yaml:"stop,omitempty" description:"Conditions that end a load mode run before its duration"`
+	Warmup      WarmupConfig     `yaml:"warmup,omitempty" description:"Traffic sent before measurement of load and search mode runs, reported apart from the measured requests"`
+	// Seed is a pointer so that 0 is a seed like any other; a run whose
+	// random seed was 0 can be reproduced.
+	Seed *int64 `yaml:"seed,omitempty" description:"Seed of the random choices of load and search mode runs: endpoint selection, feeder rows and the random template functions; unset picks a random seed, which is recorded in the report"`
+}
+
+// ReplayConfig describes the request log reissued in replay mode.
+type ReplayConfig struct {
+	File   string  `yaml:"file" description:"nginx/Apache combined access log or NDJSON request log to replay"`
+	Format string  `yaml:"format,omitempty" description:"Log format; detected from the first line when empty" jsonschema:"enum=combined|ndjson"`
+	Timing string  `yaml:"timing,omitempty" description:"original keeps the logged inter-arrival times scaled by speed; rps replays the request mix at loadtest.rps for loadtest.duration" jsonschema:"enum=original|rps,default=original"`
+	Speed  float64 `yaml:"speed,omitempty" description:"Speed factor for original timing; 2 replays twice as fast" jsonschema:"exclusiveMinimum=0,default=1"`
+}
+
+// SearchConfig describes a capacity search. Every step sends the weighted
+// endpoint mix at one rate for the step duration and passes when the SLO is
+// met; the result is the highest passing rate.
+type SearchConfig struct {
+	Strategy     string    `yaml:"strategy,omitempty" description:"ramp raises the rate by step until a step fails; binary halves the range between the highest passing and lowest failing rate" jsonschema:"enum=ramp|binary,default=ramp"`
+	StartRPS     int       `yaml:"start_rps,omitempty" description:"Rate of the first step; 0 means loadtest.rps" jsonschema:"minimum=0"`
+	MaxRPS       int       `yaml:"max_rps" description:"Highest rate tried" jsonschema:"exclusiveMinimum=0"`
+	Step         int       `yaml:"step,omitempty" description:"Rate increase between ramp steps; 0 means start_rps" jsonschema:"minimum=0"`
+	StepDuration int       `yaml:"step_duration,omitempty" description:"Seconds each step is held; 0 means loadtest.duration" jsonschema:"minimum=0"`
+	Precision    int       `yaml:"precision,omitempty" description:"Binary search stops once the passing and failing rates are at most this far apart; 0 means 5% of max_rps" jsonschema:"minimum=0"`
+	SLO          SLOConfig `yaml:"slo,omitempty" description:"Criteria every step must meet"`
+}
+
+// SLOConfig are the criteria a search step must meet.
+type SLOConfig struct {
+	P99 int `yaml:"p99,omitempty" description:"Highest acceptable 99th percentile latency in milliseconds; 0 disables the check" jsonschema:"minimum=0"`
+	// ErrorRate is a pointer so that an explicit 0, which tolerates no
+	// failed request at all, can be told apart from an unset value.
+	ErrorRate *float64 `yaml:"error_rate,omitempty" description:"Highest acceptable percentage of failed requests, 0 tolerates none; unset means 1" jsonschema:"minimum=0,maximum=100,default=1"`
+	// Throughput compares the achieved rate with the step rate, so that a
+	// target that cannot keep up fails even when its responses are fast.
+	Throughput float64 `yaml:"throughput,omitempty" description:"Lowest acceptable achieved rate as a fraction of the step rate; 0 means 0.95" jsonschema:"minimum=0,maximum=1,default=0.95"`
+}
+
+// Defaults of the search settings.
+const (
+	defaultSLOErrorRate  = 1
+	defaultSLOThroughput = 0.95
+)
+
+// SearchStartRPS returns the rate of the first step.
+func (c LoadTestConfig) SearchStartRPS() int {
+	if c.Search.StartRPS > 0 {
+		return c.Search.StartRPS
+	}
+	return c.RPS
+}
+
+// SearchStep returns the rate increase between ramp steps.
+func (c LoadTestConfig) SearchStep() int {
+	if c.Search.Step > 0 {
+		return c.Search.Step
+	}
+	return c.SearchStartRPS()
+}
+
+// SearchStepDuration returns the seconds each step is held.
+func (c LoadTestConfig) SearchStepDuration() int {
+	if c.Search.StepDuration > 0 {
+		return c.Search.StepDuration
+	}
+	return c.Duration
+}
+
+// SearchPrecision returns the distance at which binary search stops.
+func (c LoadTestConfig) SearchPrecision() int {
+	if c.Search.Precision > 0 {
+		return c.Search.Precision
+	}
+	return max(1, c.Search.MaxRPS/20)
+}
+
+// MaxErrorRate returns the highest acceptable error percentage.
+func (s SLOConfig) MaxErrorRate() float64 {
+	if s.ErrorRate != nil {
+		return *s.ErrorRate
+	}
+	return defaultSLOErrorRate
+}
+
+// MinThroughput returns the lowest acceptable ratio of achieved to target
+// rate.
+func (s SLOConfig) MinThroughput() float64 {
+	if s.Throughput > 0 {
+		return s.Throughput
+	}
+	return defaultSLOThroughput
+}
+
+// CheckpointConfig makes long runs write an interim report at a fixed
+// interval. The requests of every interval are written to a raw result file
+// and only their aggregate is kept in memory.
+type CheckpointConfig struct {
+	Interval int    `yaml:"interval,omitempty" description:"Seconds between checkpoints; 0 disables checkpoints" jsonschema:"minimum=0"`
+	Dir      string `yaml:"dir,omitempty" description:"Directory the interim reports and raw result files are written to" jsonschema:"default=checkpoints"`
+	Keep     int    `yaml:"keep,omitempty" description:"Number of raw result files kept, older files are deleted; 0 keeps all" jsonschema:"minimum=0"`
+}
+
+// defaultCheckpointDir is the directory checkpoints are written to when the
+// config does not say.
+const defaultCheckpointDir = "checkpoints"
+
+// Directory returns the directory checkpoints are written to.
+func (c CheckpointConfig) Directory() string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	return defaultCheckpointDir
+}
+
+// StopConfig are the conditions that end a run early. The run ends at the
+// duration or at the first condition met, whichever comes first.
+type StopConfig struct {
+	MaxRequests       int     `yaml:"max_requests,omitempty" description:"Total number of requests to send; 0 means no limit" jsonschema:"minimum=0"`
+	Iterations        int     `yaml:"iterations,omitempty" description:"Number of requests every worker sends; the run ends once all workers are done. 0 means no limit" jsonschema:"minimum=0"`
+	ConsecutiveErrors int     `yaml:"consecutive_errors,omitempty" description:"Abort after this many failed requests in a row; 0 disables the check" jsonschema:"minimum=0"`
+	ErrorRate         float64 `yaml:"error_rate,omitempty" description:"Abort when the percentage of failed requests within the window goes above this; 0 disables the check" jsonschema:"minimum=0,maximum=100"`
+	Window            int     `yaml:"window,omitempty" description:"Seconds of the sliding window error_rate is measured over; 0 means 10" jsonschema:"minimum=0,default=10"`
+	// MinRequests keeps the rate checks from acting on a handful of
+	// requests, e.g. a single early failure.
+	MinRequests int             `yaml:"min_requests,omitempty" description:"Requests needed before error_rate and thresholds are checked; 0 means 10" jsonschema:"minimum=0,default=10"`
+	Thresholds  ThresholdConfig `yaml:"thresholds,omitempty" description:"Abort when the results of the run so far breach one of these"`
+}
+
+// ThresholdConfig are limits on the results of the whole run so far.
+type ThresholdConfig struct {
+	P95       int     `yaml:"p95,omitempty" description:"Highest acceptable 95th percentile latency in milliseconds; 0 disables the check" jsonschema:"minimum=0"`
+	P99       int     `yaml:"p99,omitempty" description:"Highest acceptable 99th percentile latency in milliseconds; 0 disables the check" jsonschema:"minimum=0"`
+	ErrorRate float64 `yaml:"error_rate,omitempty" description:"Highest acceptable percentage of failed requests; 0 disables the check" jsonschema:"minimum=0,maximum=100"`
+}
+
+// Defaults of the stop conditions.
+const (
+	defaultStopWindow      = 10
+	defaultStopMinRequests = 10
+)
+
+// WindowSeconds returns the length of the error rate window.
+func (s StopConfig) WindowSeconds() int {
+	if s.Window > 0 {
+		return s.Window
+	}
+	return defaultStopWindow
+}
+
+// MinimumRequests returns the number of requests the rate checks need.
+func (s StopConfig) MinimumRequests() int {
+	if s.MinRequests > 0 {
+		return s.MinRequests
+	}
+	return defaultStopMinRequests
+}
+
+// WarmupConfig is traffic sent before measurement starts, so that
+// connection setup and cold caches on the target do not skew the
+// statistics of the run. Endpoints with a rate of their own keep that rate
+// during the warm-up.
+type WarmupConfig struct {
+	Duration int  `yaml:"duration,omitempty" description:"Seconds of warm-up before measurement; 0 disables the warm-up" jsonschema:"minimum=0"`
+	RPS      int  `yaml:"rps,omitempty" description:"Requests per second of the weighted endpoints during the warm-up; 0 means the rate of the run, or of the first step in search mode" jsonschema:"minimum=0"`
+	Include  bool `yaml:"include,omitempty" description:"Count the warm-up requests in the statistics of the run"`
+}
+
+// WarmupRPS returns the rate of the weighted endpoints during the warm-up.
+func (c LoadTestConfig) WarmupRPS() int {
+	if c.Warmup.RPS > 0 {
+		return c.Warmup.RPS
+	}
+	if c.RunMode() == ModeSearch {
+		return c.SearchStartRPS()
+	}
+	return c.RPS
+}
+
+// FixedRPS returns the total rate of the endpoints with a rate of their own.
+func (c LoadTestConfig) FixedRPS() float64 {
+	total := 0.0
+	for _, ep := range c.Endpoints {
+		total += ep.RPS
+	}
+	return total
+}
+
+// RunMode returns the test mode, defaulting to load.
+func (c LoadTestConfig) RunMode() string {
+	if c.Mode == "" {
+		return ModeLoad
+	}
+	return c.Mode
+}
+
+type Endpoint struct {
+	Type    string            `yaml:"type,omitempty" description:"Protocol of the endpoint" jsonschema:"enum=http|grpc|websocket|sse|graphql|dns|tcp|udp,default=http"`
+	Method  string            `yaml:"method,omitempty" description:"HTTP method" jsonschema:"enum=GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS,default=GET"`
+	Path    string            `yaml:"path" description:"Path appended to the domain; for gRPC the full method name, e.g. /package.Service/Method; unused for dns, tcp and udp"`
+	Headers map[string]string `yaml:"headers,omitempty" description:"Request headers; gRPC metadata for gRPC endpoints, handshake headers for WebSocket endpoints"`
+	Body    string            `yaml:"body,omitempty" description:"Request body; for gRPC the request message as JSON, or a JSON array of messages for client streaming; the payload for tcp and udp"`
+	Weight  float64           `yaml:"weight" description:"Relative share of requests sent to this endpoint; 0 means 1.0" jsonschema:"minimum=0,default=1"`
+	// RPS takes the endpoint out of the weighted mix; it is sent at its own
+	// rate in addition to loadtest.rps.
+	RPS float64 `yaml:"rps,omitempty" description:"Fixed rate of this endpoint in requests per second, independent of loadtest.rps and the weights; 0 means the endpoint is weighted" jsonschema:"minimum=0"`
+	// Concurrency gives the endpoint workers of its own instead of the
+	// shared ones, which caps its requests in flight.
+	Concurrency int             `yaml:"concurrency,omitempty" description:"Maximum requests to this endpoint in flight; its requests are sent by that many workers of their own. 0 means the shared workers" jsonschema:"minimum=0"`
+	GRPC        GRPCConfig      `yaml:"grpc,omitempty" description:"gRPC settings"`
+	WebSocket   WebSocketConfig `yaml:"websocket,omitempty" description:"WebSocket settings"`
+	SSE         SSEConfig       `yaml:"sse,omitempty" description:"Streaming settings"`
+	GraphQL     GraphQLConfig   `yaml:"graphql,omitempty" description:"GraphQL settings"`
+	DNS         DNSConfig       `yaml:"dns,omitempty" description:"DNS query settings"`
+	TCP         TCPConfig       `yaml:"tcp,omitempty" description:"TCP settings"`
+	UDP         UDPConfig       `yaml:"udp,omitempty" description:"UDP settings"`
+	// ExpectCookies are checked against the cookie jar after the response,
+	// so a cookie set by an earlier request also satisfies the check.
+	ExpectCookies []string `yaml:"expect_cookies,omitempty" description:"Cookies the worker must hold for the endpoint URL after the response; a missing cookie fails the request. Names may be request templates. Requires loadtest.cookies.enabled"`
+	// ExpectBody is rendered with the request, so that it can check for
+	// the values the request was sent with, such as a feeder column.
+	ExpectBody string `yaml:"expect_body,omitempty" description:"Text the response body must contain, for http and graphql endpoints; a request template rendered with the functions and feeder rows of the request. A body without it fails the request"`
+}
+
+// GRPCConfig describes how the messages of a gRPC endpoint are resolved.
+type GRPCConfig struct {
+	DescriptorSet string `yaml:"descriptor_set,omitempty" description:"FileDescriptorSet file (protoc --descriptor_set_out --include_imports); server reflection is used when empty"`
+}
+
+// WebSocketConfig describes the session of a websocket endpoint. Every
+// request to the endpoint opens a connection that sends the messages in
+// order, one per interval and wrapping around, until the connection has been
+// open for the configured duration.
+type WebSocketConfig struct {
+	Messages []string `yaml:"messages,omitempty" description:"Text messages sent after connecting; the server is expected to echo each one back"`
+	Interval int      `yaml:"interval,omitempty" description:"Milliseconds between messages; 0 means 1000" jsonschema:"minimum=0,default=1000"`
+	Duration int      `yaml:"duration,omitempty" description:"Seconds each connection is kept open; 0 means until the messages have been sent once" jsonschema:"minimum=0"`
+}
+
+// SSEConfig describes how long the stream of an sse endpoint is read.
+type SSEConfig struct {
+	Duration int `yaml:"duration,omitempty" description:"Seconds after which the client closes the stream; 0 reads until the server ends it or the test duration runs out" jsonschema:"minimum=0"`
+}
+
+// GraphQLConfig is the operation a graphql endpoint sends. It is posted as
+// JSON to the endpoint path.
+type GraphQLConfig struct {
+	Query         string         `yaml:"query,omitempty" description:"GraphQL query document"`
+	OperationName string         `yaml:"operation_name,omitempty" description:"Operation to execute when the document defines several; also names the operation in the report"`
+	Variables     map[string]any `yaml:"variables,omitempty" description:"Operation variables"`
+}
+
+// Operation returns the name the operation is reported under: the
+// operation name, else the name of the first operation in the query, else
+// "anonymous".
+func (g GraphQLConfig) Operation() string {
+	if g.OperationName != "" {
+		return g.OperationName
+	}
+	if m := operationNamePattern.FindStringSubmatch(g.Query); m != nil {
+		return m[1]
+	}
+	return "anonymous"
+}
+
+var operationNamePattern = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// DNSConfig is the query a dns endpoint sends to a resolver.
+type DNSConfig struct {
+	Resolver string `yaml:"resolver,omitempty" description:"Resolver address as host or host:port (port 53 by default)"`
+	Name     string `yaml:"name,omitempty" description:"Domain name to query"`
+	Type     string `yaml:"type,omitempty" description:"Record type" jsonschema:"enum=A|AAAA|CNAME|MX|NS|PTR|SOA|SRV|TXT|CAA|ANY,default=A"`
+}
+
+// QueryType returns the record type, defaulting to A.
+func (d DNSConfig) QueryType() string {
+	if d.Type == "" {
+		return "A"
+	}
+	return strings.ToUpper(d.Type)
+}
+
+// ResolverAddress returns the resolver as host:port.
+func (d DNSConfig) ResolverAddress() string {
+	if _, _, err := net.SplitHostPort(d.Resolver); err == nil {
+		return d.Resolver
+	}
+	return net.JoinHostPort(strings.Trim(d.Resolver, "[]"), "53")
+}
+
+// TCPConfig describes the request/response exchange of a tcp endpoint. The
+// body is sent as is and the response is read up to the delimiter.
+type TCPConfig struct {
+	Address   string `yaml:"address,omitempty" description:"Server address as host:port"`
+	Delimiter string `yaml:"delimiter,omitempty" description:"End of a response; a newline when empty"`
+}
+
+// ResponseDelimiter returns the delimiter, defaulting to a newline.
+func (t TCPConfig) ResponseDelimiter() string {
+	if t.Delimiter == "" {
+		return "\n"
+	}
+	return t.Delimiter
+}
+
+// UDPConfig describes the datagrams of a udp endpoint. The body is sent as
+// one datagram.
+type UDPConfig struct {
+	Address        string `yaml:"address,omitempty" description:"Server address as host:port"`
+	ExpectResponse bool   `yaml:"expect_response,omitempty" description:"Wait for a response datagram; a request fails when none arrives before the timeout"`
+}
+
+// CookieConfig enables a cookie jar per worker. Cookies set by responses
+// are sent with the later requests of the same worker, so that each worker
+// behaves like one user with its own session.
+type CookieConfig struct {
+	Enabled bool     `yaml:"enabled,omitempty" description:"Give every worker its own cookie jar for http, graphql, sse and websocket endpoints"`
+	Reset   string   `yaml:"reset,omitempty" description:"never keeps the cookies for the whole run; iteration starts every iteration of the worker loop (one request) from the seeded cookies" jsonschema:"enum=never|iteration,default=never"`
+	Seed    []Cookie `yaml:"seed,omitempty" description:"Cookies every jar starts with"`
+}
+
+// AuthConfig describes the credentials sent with every request. Headers an
+// endpoint sets itself take precedence.
+type AuthConfig struct {
+	Type     string       `yaml:"type,omitempty" description:"Authentication scheme; no credentials are added when empty" jsonschema:"enum=bearer|basic|api_key|oauth2"`
+	Token    string       `yaml:"token,omitempty" description:"Token sent as Authorization: Bearer for bearer"`
+	Username string       `yaml:"username,omitempty" description:"User name for basic"`
+	Password string       `yaml:"password,omitempty" description:"Password for basic"`
+	APIKey   APIKeyConfig `yaml:"api_key,omitempty" description:"API key settings for api_key"`
+	OAuth2   OAuth2Config `yaml:"oauth2,omitempty" description:"Client credentials settings for oauth2"`
+}
+
+// APIKeyConfig is an API key sent in a header or a query parameter.
+type APIKeyConfig struct {
+	Name  string `yaml:"name" description:"Header or query parameter name"`
+	Value string `yaml:"value" description:"API key"`
+	In    string `yaml:"in,omitempty" description:"Where the key is sent" jsonschema:"enum=header|query,default=header"`
+}
+
+// OAuth2Config describes an OAuth2 client credentials grant. The access
+// token is shared by all workers and fetched again before it expires.
+type OAuth2Config struct {
+	TokenURL      string   `yaml:"token_url" description:"Token endpoint" jsonschema:"format=uri"`
+	ClientID      string   `yaml:"client_id" description:"Client ID, sent with HTTP basic authentication"`
+	ClientSecret  string   `yaml:"client_secret" description:"Client secret"`
+	Scopes        []string `yaml:"scopes,omitempty" description:"Requested scopes"`
+	RefreshBefore int      `yaml:"refresh_before,omitempty" description:"Seconds before expiry at which the token is refreshed; 0 means 30, capped at half the token lifetime" jsonschema:"minimum=0,default=30"`
+}
+
+// Cookie is a cookie the jar of every worker starts with.
+type Cookie struct {
+	Name   string `yaml:"name" description:"Cookie name"`
+	Value  string `yaml:"value" description:"Cookie value"`
+	Domain string `yaml:"domain,omitempty" description:"Host the cookie is sent to, including its subdomains; the host of loadtest.domain when empty"`
+	Path   string `yaml:"path,omitempty" description:"Path prefix the cookie is sent to; / when empty"`
+}
+
+// Feeder is a CSV file of test data. The first row names the columns;
+// every request that uses the feeder reads one of the following rows.
+type Feeder struct {
+	Name  string `yaml:"name" description:"Name used in templates: {{feeder \"name\" \"column\"}}"`
+	File  string `yaml:"file" description:"CSV file with a header row"`
+	Order string `yaml:"order,omitempty" description:"sequential reads the rows in order, wrapping around; random picks a row per request" jsonschema:"enum=sequential|random,default=sequential"`
+}
+
+// Protocol returns the endpoint type, defaulting to http.
+func (e Endpoint) Protocol() string {
+	if e.Type == "" {
+		return EndpointHTTP
+	}
+	return e.Type
+}
+
+// URL returns the URL requests to the endpoint are reported under. DNS,
+// TCP and UDP endpoints do not use the domain and are identified by their
+// own address.
+func (e Endpoint) URL(domain string) string {
+	switch e.Protocol() {
+	case EndpointDNS:
+		return "dns://" + e.DNS.ResolverAddress() + "/" + e.DNS.Name + "?type=" + e.DNS.QueryType()
+	case EndpointTCP:
+		return "tcp://" + e.TCP.Address
+	case EndpointUDP:
+		return "udp://" + e.UDP.Address
+	}
+	return domain + e.Path
+}
+
+// templateEnvCall matches an env call of a request template with a literal
+// variable name.
+var templateEnvCall = regexp.MustCompile(`\benv\s+"([^"]*)"`)
+
+// TemplateEnv returns the names of the environment variables the request
+// templates of the endpoint read. Templates can only read variables they
+// name literally, so that their values can be masked like secrets.
+func (e Endpoint) TemplateEnv() []string {
+	texts := append([]string{e.Path, e.Body, e.ExpectBody}, e.ExpectCookies...)
+	for _, value := range e.Headers {
+		texts = append(texts, value)
+	}
+	var names []string
+	for _, text := range texts {
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+		for _, m := range templateEnvCall.FindAllStringSubmatch(text, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// RequestMethod returns the HTTP method of the endpoint, defaulting to GET.
+func (e Endpoint) RequestMethod() string {
+	if e.Method == "" {
+		return "GET"
+	}
+	return e.Method
+}
+
+// Default returns the configuration used when no config file overrides it.
+func Default() *Config {
+	return &Config{
+		LoadTest: LoadTestConfig{
+			Domain: "http://localhost:8080",
+			Endpoints: []Endpoint{
+				{Path: "/", Weight: 1.0},
+			},
+			RPS:         10,
+			Concurrency: 1,
+			Duration:    10,
+			Output:      "html",
+		},
+	}
+}
+
+func LoadConfig(cfgFile string) (*Config, error) {
+	cfg := Default()
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = findConfigFile()
+	}
+
+	if configPath == "" {
+		fmt.Fprintf(os.Stderr, "Warning: No config file found, using defaults\n")
+	} else {
+		root, err := parseFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if root.Kind != 0 {
+			var issues []Issue
+			interpolate(root, &cfg.Secrets, &issues)
+			if len(issues) > 0 {
+				return nil, fmt.Errorf("error interpolating config file: %s", issues[0])
+			}
+
+			if err := root.Decode(cfg); err != nil {
+				return nil, fmt.Errorf("error parsing config file: %w", err)
+			}
+			cfg.addCredentialSecrets()
+		}
+		cfg.Path = configPath
+	}
+
+	if err := cfg.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func parseFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+	return &root, nil
+}
+
+// applyEnvOverrides applies METEOR_SHOWER_* environment variables on top of
+// the values loaded from the config file.
+func (c *Config) applyEnvOverrides() error {
+	if v, ok := os.LookupEnv(envPrefix + "DOMAIN"); ok {
+		c.LoadTest.Domain = v
+		c.EnvOverrides = append(c.EnvOverrides, envPrefix+"DOMAIN")
+	}
+	if v, ok := os.LookupEnv(envPrefix + "OUTPUT"); ok {
+		c.LoadTest.Output = v
+		c.EnvOverrides = append(c.EnvOverrides, envPrefix+"OUTPUT")
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"RPS", &c.LoadTest.RPS},
+		{"CONCURRENCY", &c.LoadTest.Concurrency},
+		{"DURATION", &c.LoadTest.Duration},
+	}
+	for _, field := range ints {
+		v, ok := os.LookupEnv(envPrefix + field.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s%s: %q is not an integer", envPrefix, field.name, v)
+		}
+		*field.value = n
+		c.EnvOverrides = append(c.EnvOverrides, envPrefix+field.name)
+	}
+
+	return nil
+}
+
+func findConfigFile() string {
+	candidates := []string{
+		"config.yaml",
+		"config.yml",
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err == nil {
+		candidates = append(candidates,
+			filepath.Join(homeDir, ".meteor-shower", "config.yaml"),
+			filepath.Join(homeDir, ".meteor-shower", "config.yml"),
+		)
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// Encode writes cfg as a YAML config file preceded by header, which is
+// emitted as comment lines. Secret values are masked.
+func Encode(w io.Writer, cfg *Config, header ...string) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	for _, line := range header {
+		if _, err := fmt.Fprintf(w, "# %s\n", line); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, cfg.Secrets.Mask(buf.String()))
+	return err
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "****"
+
+// Secrets holds values resolved from secret sources so they can be masked
+// before being printed or written into reports.
+type Secrets []string
+
+// Mask replaces every secret value contained in text with a fixed mask.
+func (s Secrets) Mask(text string) string {
+	for _, secret := range s {
+		if secret == "" {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, secretMask)
+	}
+	return text
+}
+
+func (s *Secrets) add(value string) {
+	if value == "" {
+		return
+	}
+	for _, v := range *s {
+		if v == value {
+			return
+		}
+	}
+	*s = append(*s, value)
+}
+
+var (
+	interpolationPattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+	secretNamePattern    = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|PRIVATE_KEY|CREDENTIAL|AUTH)`)
+)
+
+// interpolate expands ${ENV_VAR}, ${ENV_VAR:-default} and ${file:/path}
+// references in every scalar of the YAML document; $${...} is kept as a
+// literal ${...}. Values read from files, and environment variables whose
+// name looks like a credential, are recorded as secrets. Every reference
+// that cannot be resolved is reported as an issue so that all of them can
+// be shown at once.
+func interpolate(node *yaml.Node, secrets *Secrets, issues *[]Issue) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode, yaml.MappingNode:
+		for i, child := range node.Content {
+			// Mapping keys are never interpolated.
+			if node.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+			interpolate(child, secrets, issues)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		value, err := expand(node.Value, secrets)
+		if err != nil {
+			*issues = append(*issues, Issue{Line: node.Line, Message: err.Error()})
+			return
+		}
+		node.Value = value
+		// Let plain scalars such as `rps: ${RPS}` resolve to their real type.
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+}
+
+func expand(value string, secrets *Secrets) (string, error) {
+	var expandErr error
+	result := interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		// $${...} escapes a literal ${...}.
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		resolved, err := resolveReference(match[2:len(match)-1], secrets)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		return resolved
+	})
+	return result, expandErr
+}
+
+func resolveReference(ref string, secrets *Secrets) (string, error) {
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		secrets.add(value)
+		return value, nil
+	}
+
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty variable reference ${%s}", ref)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if hasDefault && value == "" {
+		return def, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	if secretNamePattern.MatchString(name) {
+		secrets.add(value)
+	}
+	return value, nil
+}
+
+// IsCredentialHeader reports whether the value of the header name is a
+// credential: Authorization, Proxy-Authorization, Cookie and any header
+// ending in -Key or -Token.
+func IsCredentialHeader(name string) bool {
+	canonical := http.CanonicalHeaderKey(name)
+	switch canonical {
+	case "Authorization", "Proxy-Authorization", "Cookie":
+		return true
+	}
+	return strings.HasSuffix(canonical, "-Key") || strings.HasSuffix(canonical, "-Token")
+}
+
+// addCredentialSecrets records the values of every credential field as
+// secrets, whether they are written literally or interpolated from a
+// variable whose name does not look like a credential: the auth settings,
+// the seeded cookies, credential headers and the environment variables
+// request templates read.
+func (c *Config) addCredentialSecrets() {
+	lt := c.LoadTest
+	auth := lt.Auth
+	for _, value := range []string{auth.Token, auth.Password, auth.APIKey.Value, auth.OAuth2.ClientSecret} {
+		c.Secrets.add(value)
+	}
+	if auth.Password != "" {
+		c.Secrets.add(base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password)))
+	}
+	for _, cookie := range lt.Cookies.Seed {
+		c.Secrets.add(cookie.Value)
+	}
+	for _, ep := range lt.Endpoints {
+		for name, value := range ep.Headers {
+			if !IsCredentialHeader(name) || strings.Contains(value, "{{") {
+				continue
+			}
+			c.Secrets.add(value)
+			// The credential of an Authorization header also appears
+			// without its scheme.
+			switch http.CanonicalHeaderKey(name) {
+			case "Authorization", "Proxy-Authorization":
+				if _, credential, ok := strings.Cut(value, " "); ok {
+					c.Secrets.add(strings.TrimSpace(credential))
+				}
+			}
+		}
+		for _, name := range ep.TemplateEnv() {
+			c.Secrets.add(os.Getenv(name))
+		}
+	}
+}
+
+// Escape protects literal ${...} sequences in value from interpolation.
+func Escape(value string) string {
+	return strings.ReplaceAll(value, "${", "$${")
+}
+
+// EscapeTemplate protects literal {{ sequences in value from being read as
+// a request template: the template prints them instead.
+func EscapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "{{", `{{"{{"}}`)
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	schemaDraft = "https://json-schema.org/draft-07/schema#"
+
+	// SchemaURL is where the published schema for this version of the config
+	// format can be fetched by editors.
+	SchemaURL = "https://raw.githubusercontent.com/kitsystemyou/meteor-shower/main/config.schema.json"
+)
+
+// Schema is the subset of JSON Schema (draft-07) used to describe the
+// config file.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+
+	// closed marks objects that reject unknown keys. It is emitted as
+	// "additionalProperties": false.
+	closed bool
+}
+
+// MarshalJSON emits closed objects with "additionalProperties": false.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	if !s.closed {
+		return json.Marshal((*plain)(s))
+	}
+	return json.Marshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{plain: (*plain)(s)})
+}
+
+// GenerateSchema builds the JSON Schema of the config file from the Config
+// struct, taking descriptions and constraints from struct tags and default
+// values from Default.
+func GenerateSchema() *Schema {
+	schema := schemaFor(reflect.TypeOf(Config{}), reflect.ValueOf(*Default()))
+	schema.Schema = schemaDraft
+	schema.ID = SchemaURL
+	schema.Title = "meteor-shower configuration"
+	return schema
+}
+
+func schemaFor(t reflect.Type, def reflect.Value) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if def.IsValid() {
+			if def.IsNil() {
+				def = reflect.Value{}
+			} else {
+				def = def.Elem()
+			}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), closed: true}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := yamlName(f)
+			if !ok {
+				continue
+			}
+			var fieldDef reflect.Value
+			if def.IsValid() {
+				fieldDef = def.Field(i)
+			}
+			if strings.Contains(f.Tag.Get("yaml"), "inline") {
+				inlined := schemaFor(f.Type, fieldDef)
+				for k, v := range inlined.Properties {
+					s.Properties[k] = v
+				}
+				continue
+			}
+			prop := schemaFor(f.Type, fieldDef)
+			prop.Description = f.Tag.Get("description")
+			applySchemaTag(prop, f.Tag.Get("jsonschema"))
+			s.Properties[name] = prop
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem(), reflect.Value{})}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem(), reflect.Value{})}
+	case reflect.String:
+		return &Schema{Type: "string", Default: scalarDefault(def)}
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Default: scalarDefault(def)}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Default: scalarDefault(def)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Default: scalarDefault(def)}
+	}
+	return &Schema{}
+}
+
+func scalarDefault(v reflect.Value) any {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// applySchemaTag applies a `jsonschema:"key=value,..."` tag. Enum values are
+// separated by "|".
+func applySchemaTag(s *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				s.Enum = append(s.Enum, v)
+			}
+		case "format":
+			s.Format = value
+		case "default":
+			s.Default = parseSchemaValue(s.Type, value)
+		case "minimum":
+			s.Minimum = parseFloat(value)
+		case "exclusiveMinimum":
+			s.ExclusiveMinimum = parseFloat(value)
+		case "maximum":
+			s.Maximum = parseFloat(value)
+		case "minItems":
+			n, _ := strconv.Atoi(value)
+			s.MinItems = &n
+		}
+	}
+}
+
+func parseSchemaValue(typ, value string) any {
+	switch typ {
+	case "integer":
+		n, _ := strconv.Atoi(value)
+		return n
+	case "number":
+		return *parseFloat(value)
+	case "boolean":
+		return value == "true"
+	}
+	return value
+}
+
+func parseFloat(value string) *float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return &f
+}
+
+func yamlName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, true
+}
+
+// validateNode checks a YAML node against schema and reports every
+// violation with the line it occurs on.
+func validateNode(node *yaml.Node, schema *Schema, path string, issues *[]Issue) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	report := func(line int, field, format string, args ...any) {
+		*issues = append(*issues, Issue{Line: line, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			report(node.Line, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field := joinPath(path, key.Value)
+			if prop, ok := schema.Properties[key.Value]; ok {
+				validateNode(value, prop, field, issues)
+			} else if schema.AdditionalProperties != nil {
+				validateNode(value, schema.AdditionalProperties, field, issues)
+			} else if schema.closed {
+				report(key.Line, field, "unknown field %q", key.Value)
+			}
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			report(node.Line, path, "expected a list")
+			return
+		}
+		if schema.MinItems != nil && len(node.Content) < *schema.MinItems {
+			report(node.Line, path, "must contain at least %d item(s)", *schema.MinItems)
+		}
+		for i, item := range node.Content {
+			validateNode(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+	case "string", "integer", "number", "boolean":
+		if node.Kind != yaml.ScalarNode {
+			report(node.Line, path, "expected type %s", schema.Type)
+			return
+		}
+		tag := node.ShortTag()
+		switch {
+		case schema.Type == "integer" && tag != "!!int",
+			schema.Type == "number" && tag != "!!int" && tag != "!!float",
+			schema.Type == "boolean" && tag != "!!bool":
+			report(node.Line, path, "expected type %s, got %q", schema.Type, node.Value)
+			return
+		}
+		if len(schema.Enum) > 0 && !enumContains(schema.Enum, node.Value) {
+			report(node.Line, path, "must be one of %s, got %q", enumString(schema.Enum), node.Value)
+		}
+		if schema.Type == "integer" || schema.Type == "number" {
+			n, err := strconv.ParseFloat(node.Value, 64)
+			if err != nil {
+				return
+			}
+			if schema.Minimum != nil && n < *schema.Minimum {
+				report(node.Line, path, "must be greater than or equal to %g", *schema.Minimum)
+			}
+			if schema.ExclusiveMinimum != nil && n <= *schema.ExclusiveMinimum {
+				report(node.Line, path, "must be greater than %g", *schema.ExclusiveMinimum)
+			}
+			if schema.Maximum != nil && n > *schema.Maximum {
+				report(node.Line, path, "must be less than or equal to %g", *schema.Maximum)
+			}
+		}
+	}
+}
+
+func enumContains(enum []any, value string) bool {
+	for _, v := range enum {
+		if fmt.Sprint(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumString(enum []any) string {
+	values := make([]string, len(enum))
+	for i, v := range enum {
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, ", ")
+}
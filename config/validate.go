@@ -0,0 +1,416 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a single problem found in a configuration.
+type Issue struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", i.Line)
+	}
+	if i.Field != "" {
+		b.WriteString(i.Field + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// JoinIssues formats issues as a single line.
+func JoinIssues(issues []Issue) string {
+	msgs := make([]string, len(issues))
+	for i, issue := range issues {
+		msgs[i] = issue.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var (
+	outputFormats = []string{"html", "json"}
+	endpointTypes = []string{EndpointHTTP, EndpointGRPC, EndpointWebSocket, EndpointSSE, EndpointGraphQL, EndpointDNS, EndpointTCP, EndpointUDP}
+	dnsTypes      = []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SOA", "SRV", "TXT", "CAA", "ANY"}
+)
+
+// Validate checks the semantic constraints of the configuration. Issues
+// carry the field path but no line number.
+func (c *Config) Validate() []Issue {
+	var issues []Issue
+	add := func(field, format string, args ...any) {
+		issues = append(issues, Issue{Field: field, Message: c.Secrets.Mask(fmt.Sprintf(format, args...))})
+	}
+
+	lt := c.LoadTest
+	if u, err := url.Parse(lt.Domain); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("loadtest.domain", "malformed domain URL %q: must be an absolute http or https URL", lt.Domain)
+	}
+	if len(lt.Endpoints) == 0 {
+		add("loadtest.endpoints", "at least one endpoint must be specified")
+	}
+	for i, ep := range lt.Endpoints {
+		field := fmt.Sprintf("loadtest.endpoints[%d]", i)
+		if ep.Weight < 0 {
+			add(field+".weight", "weight must not be negative, got %g", ep.Weight)
+		}
+		if ep.RPS < 0 {
+			add(field+".rps", "rps must not be negative, got %g", ep.RPS)
+		}
+		if ep.Concurrency < 0 {
+			add(field+".concurrency", "concurrency must not be negative, got %d", ep.Concurrency)
+		}
+		if len(ep.ExpectCookies) > 0 && !lt.Cookies.Enabled {
+			add(field+".expect_cookies", "expect_cookies requires loadtest.cookies.enabled")
+		}
+		if ep.ExpectBody != "" && ep.Protocol() != EndpointHTTP && ep.Protocol() != EndpointGraphQL {
+			add(field+".expect_body", "expect_body is only supported for http and graphql endpoints")
+		}
+		switch ep.Protocol() {
+		case EndpointHTTP:
+		case EndpointGRPC:
+			// The method is resolved before the run, so the path cannot
+			// depend on the request.
+			service, method, ok := strings.Cut(strings.TrimPrefix(ep.Path, "/"), "/")
+			if strings.Contains(ep.Path, "{{") {
+				add(field+".path", "gRPC path must not be a template, got %q", ep.Path)
+			} else if !strings.HasPrefix(ep.Path, "/") || !ok || service == "" || method == "" || strings.Contains(method, "/") {
+				add(field+".path", "gRPC path must be a full method name like /package.Service/Method, got %q", ep.Path)
+			}
+		case EndpointWebSocket:
+			if ep.WebSocket.Interval < 0 {
+				add(field+".websocket.interval", "interval must not be negative")
+			}
+			if ep.WebSocket.Duration < 0 {
+				add(field+".websocket.duration", "duration must not be negative")
+			}
+		case EndpointSSE:
+			if ep.SSE.Duration < 0 {
+				add(field+".sse.duration", "duration must not be negative")
+			}
+		case EndpointGraphQL:
+			if strings.TrimSpace(ep.GraphQL.Query) == "" {
+				add(field+".graphql.query", "query is required for GraphQL endpoints")
+			}
+		case EndpointDNS:
+			if ep.DNS.Resolver == "" {
+				add(field+".dns.resolver", "resolver is required for DNS endpoints")
+			}
+			if ep.DNS.Name == "" {
+				add(field+".dns.name", "name is required for DNS endpoints")
+			}
+			if !containsString(dnsTypes, ep.DNS.QueryType()) {
+				add(field+".dns.type", "unsupported record type %q (expected one of: %s)", ep.DNS.Type, strings.Join(dnsTypes, ", "))
+			}
+		case EndpointTCP:
+			if _, _, err := net.SplitHostPort(ep.TCP.Address); err != nil {
+				add(field+".tcp.address", "address must be host:port, got %q", ep.TCP.Address)
+			}
+		case EndpointUDP:
+			if _, _, err := net.SplitHostPort(ep.UDP.Address); err != nil {
+				add(field+".udp.address", "address must be host:port, got %q", ep.UDP.Address)
+			}
+		default:
+			add(field+".type", "unsupported endpoint type %q (expected one of: %s)", ep.Type, strings.Join(endpointTypes, ", "))
+		}
+	}
+	if lt.RPS <= 0 {
+		add("loadtest.rps", "rps must be greater than 0")
+	}
+	if lt.Concurrency <= 0 {
+		add("loadtest.concurrency", "concurrency must be greater than 0")
+	}
+	if lt.Duration <= 0 {
+		add("loadtest.duration", "duration must be greater than 0")
+	}
+	feeders := make(map[string]bool)
+	for i, f := range lt.Feeders {
+		field := fmt.Sprintf("loadtest.feeders[%d]", i)
+		switch {
+		case f.Name == "":
+			add(field+".name", "name is required")
+		case feeders[f.Name]:
+			add(field+".name", "duplicate feeder name %q", f.Name)
+		}
+		feeders[f.Name] = true
+		if f.File == "" {
+			add(field+".file", "file is required")
+		}
+		if f.Order != "" && f.Order != FeederSequential && f.Order != FeederRandom {
+			add(field+".order", "unsupported order %q (expected sequential or random)", f.Order)
+		}
+	}
+	if lt.Cookies.Reset != "" && lt.Cookies.Reset != CookieResetNever && lt.Cookies.Reset != CookieResetIteration {
+		add("loadtest.cookies.reset", "unsupported reset %q (expected never or iteration)", lt.Cookies.Reset)
+	}
+	for i, c := range lt.Cookies.Seed {
+		if c.Name == "" {
+			add(fmt.Sprintf("loadtest.cookies.seed[%d].name", i), "name is required")
+		}
+	}
+	validateAuth(lt.Auth, add)
+	if lt.Checkpoint.Interval < 0 {
+		add("loadtest.checkpoint.interval", "interval must not be negative")
+	} else if lt.Checkpoint.Interval > 0 && lt.RunMode() != ModeLoad {
+		add("loadtest.checkpoint.interval", "checkpoints are only supported in load mode")
+	}
+	if lt.Checkpoint.Keep < 0 {
+		add("loadtest.checkpoint.keep", "keep must not be negative")
+	}
+	validateStop(lt, add)
+	if lt.Warmup.Duration < 0 {
+		add("loadtest.warmup.duration", "duration must not be negative")
+	} else if lt.Warmup.Duration > 0 && lt.RunMode() == ModeReplay {
+		add("loadtest.warmup.duration", "a warm-up is only supported in load and search mode")
+	}
+	if lt.Warmup.RPS < 0 {
+		add("loadtest.warmup.rps", "rps must not be negative")
+	}
+	switch lt.RunMode() {
+	case ModeLoad:
+	case ModeReplay:
+		if lt.Replay.File == "" {
+			add("loadtest.replay.file", "file is required in replay mode")
+		}
+		if lt.Replay.Timing != "" && lt.Replay.Timing != TimingOriginal && lt.Replay.Timing != TimingRPS {
+			add("loadtest.replay.timing", "unsupported timing %q (expected original or rps)", lt.Replay.Timing)
+		}
+		if lt.Replay.Speed < 0 {
+			add("loadtest.replay.speed", "speed must not be negative")
+		}
+	case ModeSearch:
+		s := lt.Search
+		if s.Strategy != "" && s.Strategy != SearchRamp && s.Strategy != SearchBinary {
+			add("loadtest.search.strategy", "unsupported strategy %q (expected ramp or binary)", s.Strategy)
+		}
+		if len(lt.Endpoints) > 0 && !slices.ContainsFunc(lt.Endpoints, func(ep Endpoint) bool { return ep.RPS == 0 }) {
+			add("loadtest.endpoints", "search mode needs at least one endpoint without a rate of its own")
+		}
+		if s.MaxRPS <= 0 {
+			add("loadtest.search.max_rps", "max_rps must be greater than 0 in search mode")
+		} else if lt.SearchStartRPS() > s.MaxRPS {
+			add("loadtest.search.start_rps", "start rate %d is above max_rps %d", lt.SearchStartRPS(), s.MaxRPS)
+		}
+		for _, f := range []struct {
+			name  string
+			value int
+		}{{"start_rps", s.StartRPS}, {"step", s.Step}, {"step_duration", s.StepDuration}, {"precision", s.Precision}, {"slo.p99", s.SLO.P99}} {
+			if f.value < 0 {
+				add("loadtest.search."+f.name, "%s must not be negative", f.name)
+			}
+		}
+		if r := s.SLO.ErrorRate; r != nil && (*r < 0 || *r > 100) {
+			add("loadtest.search.slo.error_rate", "error_rate must be between 0 and 100")
+		}
+		if s.SLO.Throughput < 0 || s.SLO.Throughput > 1 {
+			add("loadtest.search.slo.throughput", "throughput must be between 0 and 1")
+		}
+	default:
+		add("loadtest.mode", "unsupported mode %q (expected load, replay or search)", lt.Mode)
+	}
+	if !containsString(outputFormats, lt.Output) {
+		add("loadtest.output", "unsupported output format %q (expected one of: %s)", lt.Output, strings.Join(outputFormats, ", "))
+	}
+
+	return issues
+}
+
+func validateStop(lt LoadTestConfig, add func(field, format string, args ...any)) {
+	s := lt.Stop
+	if s != (StopConfig{}) && lt.RunMode() != ModeLoad {
+		add("loadtest.stop", "stop conditions are only supported in load mode")
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+	}{{"max_requests", s.MaxRequests}, {"iterations", s.Iterations}, {"consecutive_errors", s.ConsecutiveErrors}, {"window", s.Window}, {"min_requests", s.MinRequests}, {"thresholds.p95", s.Thresholds.P95}, {"thresholds.p99", s.Thresholds.P99}} {
+		if f.value < 0 {
+			add("loadtest.stop."+f.name, "%s must not be negative", f.name)
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{{"error_rate", s.ErrorRate}, {"thresholds.error_rate", s.Thresholds.ErrorRate}} {
+		if f.value < 0 || f.value > 100 {
+			add("loadtest.stop."+f.name, "%s must be between 0 and 100", f.name)
+		}
+	}
+}
+
+func validateAuth(auth AuthConfig, add func(field, format string, args ...any)) {
+	switch auth.Type {
+	case "":
+	case AuthBearer:
+		if auth.Token == "" {
+			add("loadtest.auth.token", "token is required for bearer authentication")
+		}
+	case AuthBasic:
+		if auth.Username == "" {
+			add("loadtest.auth.username", "username is required for basic authentication")
+		}
+	case AuthAPIKey:
+		if auth.APIKey.Name == "" {
+			add("loadtest.auth.api_key.name", "name is required for api_key authentication")
+		}
+		if auth.APIKey.Value == "" {
+			add("loadtest.auth.api_key.value", "value is required for api_key authentication")
+		}
+		if auth.APIKey.In != "" && auth.APIKey.In != APIKeyHeader && auth.APIKey.In != APIKeyQuery {
+			add("loadtest.auth.api_key.in", "unsupported location %q (expected header or query)", auth.APIKey.In)
+		}
+	case AuthOAuth2:
+		if u, err := url.Parse(auth.OAuth2.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("loadtest.auth.oauth2.token_url", "malformed token URL %q: must be an absolute http or https URL", auth.OAuth2.TokenURL)
+		}
+		if auth.OAuth2.ClientID == "" {
+			add("loadtest.auth.oauth2.client_id", "client_id is required for oauth2 authentication")
+		}
+		if auth.OAuth2.RefreshBefore < 0 {
+			add("loadtest.auth.oauth2.refresh_before", "refresh_before must not be negative")
+		}
+	default:
+		add("loadtest.auth.type", "unsupported authentication type %q (expected bearer, basic, api_key or oauth2)", auth.Type)
+	}
+}
+
+// ValidateFile reports every problem in the config file at path: YAML
+// syntax errors, unresolvable references, violations of the config schema
+// (unknown keys, type mismatches, enums and ranges) and semantic errors,
+// each with the line it was found on.
+func ValidateFile(path string) ([]Issue, error) {
+	root, err := parseFile(path)
+	if err != nil {
+		var issue Issue
+		if !parseYAMLError(strings.TrimPrefix(errors.Unwrap(err).Error(), "yaml: "), &issue) {
+			return nil, err
+		}
+		return []Issue{issue}, nil
+	}
+
+	cfg := Default()
+	if root.Kind == 0 {
+		return cfg.Validate(), nil
+	}
+
+	var issues []Issue
+	interpolate(root, &cfg.Secrets, &issues)
+	validateNode(root.Content[0], GenerateSchema(), "", &issues)
+
+	// Type mismatches are already reported by the schema check, so a
+	// partially decoded config is good enough for the semantic checks.
+	if err := root.Decode(cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("error parsing config file: %w", err)
+		}
+	}
+
+	reported := make(map[string]bool)
+	for _, issue := range issues {
+		reported[issue.Field] = true
+	}
+	lines := make(map[string]int)
+	collectFieldLines(root.Content[0], "", lines)
+	for _, issue := range append(cfg.Validate(), checkFiles(cfg.LoadTest)...) {
+		if reported[issue.Field] {
+			continue
+		}
+		issue.Line = lines[issue.Field]
+		issues = append(issues, issue)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+// checkFiles reports the files the config reads at run time that cannot be
+// read: feeder files, the replay log and gRPC descriptor sets.
+func checkFiles(lt LoadTestConfig) []Issue {
+	var issues []Issue
+	check := func(field, path string) {
+		if path == "" {
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			issues = append(issues, Issue{Field: field, Message: fmt.Sprintf("cannot read file: %v", err)})
+			return
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err == nil && info.IsDir() {
+			issues = append(issues, Issue{Field: field, Message: fmt.Sprintf("cannot read file: %s is a directory", path)})
+		}
+	}
+	for i, f := range lt.Feeders {
+		check(fmt.Sprintf("loadtest.feeders[%d].file", i), f.File)
+	}
+	check("loadtest.replay.file", lt.Replay.File)
+	for i, ep := range lt.Endpoints {
+		check(fmt.Sprintf("loadtest.endpoints[%d].grpc.descriptor_set", i), ep.GRPC.DescriptorSet)
+	}
+	return issues
+}
+
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+func parseYAMLError(msg string, issue *Issue) bool {
+	m := yamlLinePattern.FindStringSubmatch(msg)
+	if m == nil {
+		return false
+	}
+	issue.Line, _ = strconv.Atoi(m[1])
+	issue.Message = m[2]
+	return true
+}
+
+// collectFieldLines records the line of every value in the document keyed
+// by its field path, e.g. "loadtest.endpoints[1].weight".
+func collectFieldLines(node *yaml.Node, path string, lines map[string]int) {
+	if path != "" {
+		lines[path] = node.Line
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			collectFieldLines(node.Content[i+1], joinPath(path, node.Content[i].Value), lines)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			collectFieldLines(item, fmt.Sprintf("%s[%d]", path, i), lines)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
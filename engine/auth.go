@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// defaultRefreshBefore is how long before expiry an OAuth2 token is
+// refreshed when the config does not say.
+const defaultRefreshBefore = 30 * time.Second
+
+// authenticator adds the configured credentials to requests. It is shared
+// by all workers of a run.
+type authenticator struct {
+	auth config.AuthConfig
+	// header and value are the credential header of the static schemes.
+	header, value string
+	oauth2        *tokenSource
+	cancel        context.CancelFunc
+}
+
+// newAuthenticator returns the authenticator for a run, or nil when no
+// authentication is configured. Token requests are passed to record until
+// the authenticator is closed.
+func (e *Engine) newAuthenticator(ctx context.Context, record func(report.RequestResult)) *authenticator {
+	auth := e.cfg.LoadTest.Auth
+	ctx, cancel := context.WithCancel(ctx)
+	a := &authenticator{auth: auth, cancel: cancel}
+	switch auth.Type {
+	case config.AuthBearer:
+		a.header, a.value = "Authorization", "Bearer "+auth.Token
+	case config.AuthBasic:
+		a.header, a.value = "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+	case config.AuthAPIKey:
+		if auth.APIKey.In != config.APIKeyQuery {
+			a.header, a.value = auth.APIKey.Name, auth.APIKey.Value
+		}
+	case config.AuthOAuth2:
+		a.oauth2 = &tokenSource{ctx: ctx, cfg: auth.OAuth2, client: e.client, record: record}
+	default:
+		cancel()
+		return nil
+	}
+	return a
+}
+
+// close aborts a token request in flight and waits until it has been
+// recorded. It does nothing on a nil authenticator.
+func (a *authenticator) close() {
+	if a == nil {
+		return
+	}
+	a.cancel()
+	if a.oauth2 != nil {
+		a.oauth2.wg.Wait()
+	}
+}
+
+// apply adds the credentials to t. Endpoint types without HTTP semantics
+// and headers the endpoint sets itself are left alone.
+func (a *authenticator) apply(ctx context.Context, t *target) error {
+	switch t.endpoint.Protocol() {
+	case config.EndpointDNS, config.EndpointTCP, config.EndpointUDP:
+		return nil
+	}
+
+	if a.auth.Type == config.AuthAPIKey && a.auth.APIKey.In == config.APIKeyQuery {
+		u, err := url.Parse(t.url)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		q.Set(a.auth.APIKey.Name, a.auth.APIKey.Value)
+		u.RawQuery = q.Encode()
+		t.url = u.String()
+		return nil
+	}
+
+	header, value := a.header, a.value
+	if a.oauth2 != nil {
+		token, err := a.oauth2.token(ctx)
+		if err != nil {
+			return err
+		}
+		header, value = "Authorization", "Bearer "+token
+	}
+	for name := range t.endpoint.Headers {
+		if strings.EqualFold(name, header) {
+			return nil
+		}
+	}
+	headers := maps.Clone(t.endpoint.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[header] = value
+	t.endpoint.Headers = headers
+	return nil
+}
+
+// tokenSource fetches OAuth2 access tokens with the client credentials
+// grant. The token is cached and fetched again shortly before it expires;
+// while the refresh is in flight the current token is still handed out, so
+// workers only wait when there is no valid token at all.
+type tokenSource struct {
+	// ctx bounds token requests; it is canceled when the run ends.
+	ctx    context.Context
+	cfg    config.OAuth2Config
+	client *http.Client
+	record func(report.RequestResult)
+
+	mu        sync.Mutex
+	value     string
+	expiry    time.Time // zero when the token does not expire
+	refreshAt time.Time
+	err       error
+	// fetching is closed when the token request in flight completes; nil
+	// when there is none.
+	fetching chan struct{}
+	wg       sync.WaitGroup
+}
+
+// token returns a valid access token, fetching one when needed.
+func (s *tokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	now := time.Now()
+	valid := s.value != "" && (s.expiry.IsZero() || now.Before(s.expiry))
+	if valid && (s.refreshAt.IsZero() || now.Before(s.refreshAt)) {
+		defer s.mu.Unlock()
+		return s.value, nil
+	}
+	if s.fetching == nil {
+		s.fetching = make(chan struct{})
+		s.wg.Add(1)
+		go s.fetch(s.fetching)
+	}
+	if valid {
+		defer s.mu.Unlock()
+		return s.value, nil
+	}
+	fetching := s.fetching
+	s.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.value == "" || (!s.expiry.IsZero() && !time.Now().Before(s.expiry)) {
+		return "", s.err
+	}
+	return s.value, nil
+}
+
+// fetch requests a new token and stores it, closing done when finished.
+func (s *tokenSource) fetch(done chan struct{}) {
+	defer s.wg.Done()
+	start := time.Now()
+	value, lifetime, status, err := s.request()
+	result := report.RequestResult{
+		Timestamp:  start,
+		Duration:   time.Since(start),
+		StatusCode: status,
+		URL:        s.cfg.TokenURL,
+	}
+	if err != nil {
+		result.ErrorClass, result.Error = classifyTokenError(err, status)
+	}
+	if s.ctx.Err() == nil || err == nil {
+		s.record(result)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = fmt.Errorf("token request to %s failed: %s", s.cfg.TokenURL, result.Error)
+	} else {
+		s.value, s.err = value, nil
+		s.expiry, s.refreshAt = time.Time{}, time.Time{}
+		if lifetime > 0 {
+			refreshBefore := defaultRefreshBefore
+			if s.cfg.RefreshBefore > 0 {
+				refreshBefore = time.Duration(s.cfg.RefreshBefore) * time.Second
+			}
+			refreshBefore = min(refreshBefore, lifetime/2)
+			s.expiry = start.Add(lifetime)
+			s.refreshAt = s.expiry.Add(-refreshBefore)
+		}
+	}
+	s.fetching = nil
+	close(done)
+}
+
+// errTokenStatus is returned for token responses with an error status.
+var errTokenStatus = errors.New("token endpoint returned an error status")
+
+// request performs the client credentials grant and returns the access
+// token, its lifetime (0 when the response does not say) and the HTTP
+// status.
+func (s *tokenSource) request() (string, time.Duration, int, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, resp.StatusCode, err
+	}
+	if resp.StatusCode >= 400 {
+		return "", 0, resp.StatusCode, errTokenStatus
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", 0, resp.StatusCode, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, resp.StatusCode, errors.New("token response has no access_token")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, resp.StatusCode, nil
+}
+
+// classifyTokenError classifies a failed token request like any other
+// request; a response that carries no token is a check failure.
+func classifyTokenError(err error, status int) (report.ErrorClass, string) {
+	switch {
+	case errors.Is(err, errTokenStatus):
+		return report.ClassifyStatus(status)
+	case status != 0:
+		return report.ErrorClassCheck, err.Error()
+	}
+	return report.ClassifyError(err, report.PhaseHeader)
+}
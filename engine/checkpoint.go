@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// checkpointer takes the checkpoints of a long run. At every checkpoint the
+// requests recorded since the previous one are written to a raw result file
+// and folded into the aggregate of the run, so that memory use does not
+// grow with the length of the run, and the interim report is written again.
+type checkpointer struct {
+	cfg      config.CheckpointConfig
+	log      io.Writer
+	mask     func(string) string
+	onResult func(report.RequestResult)
+	// results are the run results. Their Aggregate and Drift are only
+	// touched by checkpoint.
+	results *report.Results
+
+	mu       sync.Mutex
+	requests []report.RequestResult
+	tokens   []report.RequestResult
+
+	windowStart time.Time
+	files       []string
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// newCheckpointer returns a checkpointer that collects the requests of
+// results in its aggregate.
+func (e *Engine) newCheckpointer(results *report.Results) *checkpointer {
+	results.Aggregate = report.NewAggregate()
+	results.Drift = &report.Drift{}
+	return &checkpointer{
+		cfg:      e.cfg.LoadTest.Checkpoint,
+		log:      e.opts.Log,
+		mask:     e.cfg.Secrets.Mask,
+		onResult: e.opts.Hooks.OnResult,
+		results:  results,
+	}
+}
+
+// record collects a request result, safe for use from several workers.
+func (c *checkpointer) record(result report.RequestResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, result)
+	if c.onResult != nil {
+		c.onResult(result)
+	}
+}
+
+// recordToken collects a token request, safe for concurrent use.
+func (c *checkpointer) recordToken(result report.RequestResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = append(c.tokens, result)
+}
+
+// start takes a checkpoint every interval until finish is called.
+func (c *checkpointer) start() {
+	c.windowStart = time.Now()
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(time.Duration(c.cfg.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case now := <-ticker.C:
+				c.checkpoint(now)
+			}
+		}
+	}()
+}
+
+// finish stops taking checkpoints and folds the requests recorded since the
+// last one, up to end, into the results, so that the final report covers
+// the whole run. The results may only be changed once finish returns.
+func (c *checkpointer) finish(end time.Time) {
+	close(c.stop)
+	<-c.done
+	c.mu.Lock()
+	empty := len(c.requests) == 0 && len(c.tokens) == 0
+	c.mu.Unlock()
+	if !empty {
+		c.checkpoint(end)
+	}
+}
+
+// checkpoint folds the requests recorded since the previous checkpoint into
+// the results and writes the raw result file and the interim report.
+// Failures to write are logged; they do not stop the run.
+func (c *checkpointer) checkpoint(now time.Time) {
+	c.mu.Lock()
+	window := &report.Results{
+		StartTime:    c.windowStart,
+		EndTime:      now,
+		Requests:     c.requests,
+		TokenFetches: c.tokens,
+	}
+	c.requests, c.tokens = nil, nil
+	c.mu.Unlock()
+	c.windowStart = now
+
+	// Secrets are masked before anything reaches the disk.
+	window.Redact(c.mask)
+	n := len(c.results.Drift.Checkpoints) + 1
+	if err := c.writeRaw(n, window.Requests); err != nil {
+		fmt.Fprintf(c.log, "Checkpoint %d: %v\n", n, err)
+	}
+
+	c.results.Aggregate.Merge(window.Summarize())
+	cp := c.results.Drift.AddCheckpoint(window.StartTime, now, window.CalculateStatistics())
+	drift := c.results.Drift
+	fmt.Fprintf(c.log, "Checkpoint %d: %d requests, avg %s, p99 %s, errors %.2f%% (p99 trend %+.3fms/h, error rate trend %+.3fpp/h)\n",
+		n, cp.Requests, cp.AvgDuration.Round(time.Microsecond), cp.P99Duration.Round(time.Microsecond), cp.ErrorRate,
+		float64(drift.P99Slope)/float64(time.Millisecond), drift.ErrorRateSlope)
+
+	interim := *c.results
+	interim.URLs = make([]string, len(c.results.URLs))
+	for i, u := range c.results.URLs {
+		interim.URLs[i] = c.mask(u)
+	}
+	interim.EndTime = now
+	if w := c.results.Warmup; w != nil {
+		warmup := *w
+		masked := &report.Results{Requests: slices.Clone(w.Requests)}
+		masked.Redact(c.mask)
+		warmup.Requests = masked.Requests
+		interim.Warmup = &warmup
+	}
+	if err := c.writeReports(&interim); err != nil {
+		fmt.Fprintf(c.log, "Checkpoint %d: %v\n", n, err)
+	}
+}
+
+// writeRaw writes the requests of checkpoint n to a new raw result file and
+// deletes the files beyond the number to keep.
+func (c *checkpointer) writeRaw(n int, requests []report.RequestResult) error {
+	name := filepath.Join(c.cfg.Directory(), fmt.Sprintf("results-%04d.ndjson", n))
+	if err := writeFile(name, func(w io.Writer) error { return report.GenerateNDJSON(w, requests) }); err != nil {
+		return err
+	}
+	c.files = append(c.files, name)
+	for c.cfg.Keep > 0 && len(c.files) > c.cfg.Keep {
+		if err := os.Remove(c.files[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.files = c.files[1:]
+	}
+	return nil
+}
+
+// writeReports replaces the interim JSON and HTML reports.
+func (c *checkpointer) writeReports(results *report.Results) error {
+	dir := c.cfg.Directory()
+	if err := writeFile(filepath.Join(dir, "report.json"), func(w io.Writer) error { return report.GenerateJSON(w, results) }); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "report.html"), func(w io.Writer) error { return report.GenerateHTML(w, results) })
+}
+
+// writeFile writes name through a temporary file, so that readers never
+// see a partly written file.
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
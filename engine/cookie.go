@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// seedCookie is a configured cookie with the URL it is stored for.
+type seedCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// newSeedCookies resolves the configured seed cookies against the domain.
+func newSeedCookies(lt config.LoadTestConfig) ([]seedCookie, error) {
+	if !lt.Cookies.Enabled {
+		return nil, nil
+	}
+	domain, err := url.Parse(lt.Domain)
+	if err != nil {
+		return nil, err
+	}
+	seeds := make([]seedCookie, 0, len(lt.Cookies.Seed))
+	for _, c := range lt.Cookies.Seed {
+		u := *domain
+		cookie := &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path}
+		if c.Domain != "" {
+			u.Host = strings.TrimPrefix(c.Domain, ".")
+			cookie.Domain = c.Domain
+		}
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		seeds = append(seeds, seedCookie{url: &u, cookie: cookie})
+	}
+	return seeds, nil
+}
+
+// newJar returns a cookie jar holding the seed cookies, or nil when cookies
+// are disabled.
+func (e *Engine) newJar() http.CookieJar {
+	if !e.cfg.LoadTest.Cookies.Enabled {
+		return nil
+	}
+	// cookiejar.New only fails for invalid options.
+	jar, _ := cookiejar.New(nil)
+	for _, seed := range e.cookies {
+		jar.SetCookies(seed.url, []*http.Cookie{seed.cookie})
+	}
+	return jar
+}
+
+// withJar returns client with jar as its cookie jar. The copy shares the
+// transport, and with it the connection pool, of client.
+func withJar(client *http.Client, jar http.CookieJar) *http.Client {
+	if jar == nil {
+		return client
+	}
+	c := *client
+	c.Jar = jar
+	return &c
+}
+
+// missingCookie returns the first of names the jar holds no cookie for at
+// rawURL, or "" when it holds all of them.
+func missingCookie(jar http.CookieJar, rawURL string, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	if jar == nil {
+		return names[0]
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return names[0]
+	}
+	held := make(map[string]bool)
+	for _, c := range jar.Cookies(u) {
+		held[c.Name] = true
+	}
+	for _, name := range names {
+		if !held[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// checkCookies fails a request whose worker does not hold the cookies the
+// endpoint expects after the response.
+func checkCookies(t target) (report.ErrorClass, string) {
+	if name := missingCookie(t.jar, t.url, t.endpoint.ExpectCookies); name != "" {
+		return report.ErrorClassCheck, fmt.Sprintf("missing cookie %q", name)
+	}
+	return "", ""
+}
+
+// cookieValue returns the value of the cookie named name the jar holds for
+// rawURL, or "" when there is none.
+func cookieValue(jar http.CookieJar, rawURL, name string) string {
+	if jar == nil {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	for _, c := range jar.Cookies(u) {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
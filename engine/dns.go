@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// dnsTypes maps the record types accepted in the config to query types.
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+	"CAA":   dnsmessage.Type(257),
+	"ANY":   dnsmessage.TypeALL,
+}
+
+// dnsExecutor sends a query to the resolver of the endpoint and records the
+// response code. Queries go over UDP and are retried over TCP when the
+// response is truncated.
+type dnsExecutor struct {
+	timeout time.Duration
+}
+
+func (x *dnsExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	start := time.Now()
+	rcode, err := x.query(ctx, t.endpoint.DNS, t.seed)
+	result := report.RequestResult{
+		Timestamp: start,
+		Duration:  time.Since(start),
+		URL:       t.reportURL,
+		Protocol:  report.ProtocolDNS,
+	}
+	if err != nil {
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseHeader)
+	} else {
+		result.StatusCode = rcode
+		result.ErrorClass, result.Error = report.ClassifyDNSRcode(rcode)
+	}
+	record(result)
+}
+
+// query resolves the configured name and returns the response code.
+func (x *dnsExecutor) query(ctx context.Context, dns config.DNSConfig, seed uint64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	fqdn := dns.Name
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+	name, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return 0, fmt.Errorf("invalid DNS name %q: %w", dns.Name, err)
+	}
+	// The query ID comes from the request seed, which is drawn from the
+	// seeded random source of the run.
+	id := uint16(seed)
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsTypes[dns.QueryType()],
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := dnsExchange(ctx, "udp", dns.ResolverAddress(), query, id)
+	if err == nil && header.Truncated {
+		header, err = dnsExchange(ctx, "tcp", dns.ResolverAddress(), query, id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(header.RCode), nil
+}
+
+// dnsExchange sends query over network and returns the header of the
+// response with the same id.
+func dnsExchange(ctx context.Context, network, address string, query []byte, id uint16) (dnsmessage.Header, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return dnsmessage.Header{}, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		// Messages over TCP are prefixed with their length.
+		msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+		if _, err := conn.Write(append(msg, query...)); err != nil {
+			return dnsmessage.Header{}, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return dnsmessage.Header{}, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return dnsmessage.Header{}, err
+		}
+		var p dnsmessage.Parser
+		return p.Start(resp)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return dnsmessage.Header{}, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return dnsmessage.Header{}, err
+		}
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			// Ignore stray or malformed datagrams.
+			continue
+		}
+		return header, nil
+	}
+}
+
+func (x *dnsExecutor) close() error {
+	return nil
+}
@@ -0,0 +1,456 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// DryRun writes what a run would do to w without sending the load: the
+// resolved targets with their headers, the expected request mix per
+// endpoint, the rate schedule over time, the expected number of requests
+// and an estimate of the connections the run opens. Secrets and
+// credentials are masked. With validate one request is sent to every
+// endpoint and its outcome written as well; an error is returned when any
+// of them fails.
+func (e *Engine) DryRun(ctx context.Context, w io.Writer, validate bool) error {
+	lt := e.cfg.LoadTest
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if lt.RunMode() == config.ModeReplay {
+		if validate {
+			return fmt.Errorf("validation requests are not available in replay mode")
+		}
+		e.writeReplayDryRun(tw)
+		return tw.Flush()
+	}
+
+	seed := e.seed()
+	fmt.Fprintf(tw, "Mode: %s\n", lt.RunMode())
+	fmt.Fprintf(tw, "Domain: %s\n", e.cfg.Secrets.Mask(lt.Domain))
+	fmt.Fprintf(tw, "Seed: %d\n", seed)
+	e.writeTargets(tw)
+	if lt.RunMode() == config.ModeSearch {
+		e.writeSearchSchedule(tw)
+	} else {
+		e.writeLoadSchedule(tw)
+	}
+	e.writeConnections(tw)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if !validate {
+		return nil
+	}
+	return e.validate(ctx, w, seed)
+}
+
+// writeTargets writes every endpoint with the URL and headers of its
+// requests, including the credentials the authenticator adds.
+func (e *Engine) writeTargets(w io.Writer) {
+	lt := e.cfg.LoadTest
+	mask := e.cfg.Secrets.Mask
+	auth := lt.Auth
+	fmt.Fprintf(w, "\nTargets:\n")
+	for i, ep := range lt.Endpoints {
+		method, _ := describeEndpoint(lt, ep)
+		u := ep.URL(lt.Domain)
+		if ep.Protocol() == config.EndpointGraphQL {
+			u += " " + ep.GraphQL.Operation()
+		}
+		authenticated := auth.Type != ""
+		switch ep.Protocol() {
+		case config.EndpointDNS, config.EndpointTCP, config.EndpointUDP:
+			authenticated = false
+		}
+		if authenticated && auth.Type == config.AuthAPIKey && auth.APIKey.In == config.APIKeyQuery {
+			sep := "?"
+			if strings.Contains(ep.Path, "?") {
+				sep = "&"
+			}
+			u += sep + auth.APIKey.Name + "=****"
+		}
+		fmt.Fprintf(w, "  [%d] %s %s\n", i+1, method, mask(u))
+
+		for _, name := range slices.Sorted(maps.Keys(ep.Headers)) {
+			fmt.Fprintf(w, "      %s: %s\n", name, maskHeader(name, mask(ep.Headers[name])))
+		}
+		if !authenticated {
+			continue
+		}
+		header, value := describeAuth(auth)
+		if header == "" {
+			continue
+		}
+		overridden := false
+		for name := range ep.Headers {
+			overridden = overridden || strings.EqualFold(name, header)
+		}
+		if !overridden {
+			fmt.Fprintf(w, "      %s: %s\n", header, value)
+		}
+	}
+}
+
+// maskHeader masks the value of well-known credential headers, which may
+// hold a literal credential that is not a resolved secret. The scheme of an
+// Authorization header is kept.
+func maskHeader(name, value string) string {
+	if !config.IsCredentialHeader(name) {
+		return value
+	}
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " ****"
+		}
+	}
+	return "****"
+}
+
+// describeAuth returns the header the authenticator adds and its value with
+// the credential masked, or an empty header when the credential is not
+// sent in a header.
+func describeAuth(auth config.AuthConfig) (string, string) {
+	switch auth.Type {
+	case config.AuthBearer:
+		return "Authorization", "Bearer ****"
+	case config.AuthBasic:
+		return "Authorization", "Basic ****"
+	case config.AuthAPIKey:
+		if auth.APIKey.In != config.APIKeyQuery {
+			return auth.APIKey.Name, "****"
+		}
+	case config.AuthOAuth2:
+		return "Authorization", "Bearer <token from " + auth.OAuth2.TokenURL + ">"
+	}
+	return "", ""
+}
+
+// endpointShares returns the share of the weighted rate every endpoint
+// gets, 0 for endpoints with a rate of their own.
+func endpointShares(lt config.LoadTestConfig) []float64 {
+	total := 0.0
+	for _, ep := range lt.Endpoints {
+		if ep.RPS == 0 {
+			total += ep.Weight
+		}
+	}
+	shares := make([]float64, len(lt.Endpoints))
+	for i, ep := range lt.Endpoints {
+		if ep.RPS == 0 {
+			shares[i] = ep.Weight / total
+		}
+	}
+	return shares
+}
+
+// writeLoadSchedule writes the request mix, the rate over time and the
+// expected number of requests of a load run.
+func (e *Engine) writeLoadSchedule(w io.Writer) {
+	lt := e.cfg.LoadTest
+	s := e.newLoadSchedule(newRand(0), lt.RPS, lt.Duration)
+	weighted := 0
+	for _, st := range s.streams {
+		if st.target == nil {
+			weighted = st.total
+		}
+	}
+
+	fmt.Fprintf(w, "\nRequest mix:\n")
+	total := 0.0
+	for i, share := range endpointShares(lt) {
+		ep := lt.Endpoints[i]
+		if ep.RPS > 0 {
+			n := fixedRateRequests(ep.RPS, lt.Duration)
+			fmt.Fprintf(w, "  [%d]\tfixed\t\t%.2f RPS\t%d requests\n", i+1, ep.RPS, n)
+			total += float64(n)
+			continue
+		}
+		n := share * float64(weighted)
+		fmt.Fprintf(w, "  [%d]\tweight %g\t%.1f%%\t%.2f RPS\t~%.0f requests\n", i+1, ep.Weight, share*100, share*float64(lt.RPS), n)
+		total += n
+	}
+
+	fmt.Fprintf(w, "\nRate schedule:\n")
+	warmup := writeWarmupSchedule(w, lt)
+	rate := fmt.Sprintf("%g RPS", expectedRPS(lt, lt.RPS))
+	if fixed := lt.FixedRPS(); fixed > 0 && weighted > 0 {
+		rate += fmt.Sprintf(" (%d weighted + %g fixed)", lt.RPS, fixed)
+	}
+	fmt.Fprintf(w, "  %ds - %ds\t%s\n", lt.Warmup.Duration, lt.Warmup.Duration+lt.Duration, rate)
+
+	expected := fmt.Sprintf("%.0f", total)
+	if n := lt.Stop.MaxRequests; n > 0 && float64(n) < total {
+		expected = fmt.Sprintf("%d (max_requests; %.0f scheduled)", n, total)
+	}
+	if warmup > 0 {
+		expected += fmt.Sprintf(" + %.0f warm-up", warmup)
+	}
+	fmt.Fprintf(w, "\nExpected requests: %s\n", expected)
+	if lt.Stop.Iterations > 0 {
+		fmt.Fprintf(w, "  Every worker stops after %d iterations.\n", lt.Stop.Iterations)
+	}
+}
+
+// writeSearchSchedule writes the request mix and the steps of a capacity
+// search. The steps a search takes depend on the results, so the schedule
+// and the expected number of requests are those of the largest search.
+func (e *Engine) writeSearchSchedule(w io.Writer) {
+	lt := e.cfg.LoadTest
+	fmt.Fprintf(w, "\nRequest mix:\n")
+	for i, share := range endpointShares(lt) {
+		ep := lt.Endpoints[i]
+		if ep.RPS > 0 {
+			fmt.Fprintf(w, "  [%d]\tfixed\t\t%.2f RPS\n", i+1, ep.RPS)
+			continue
+		}
+		fmt.Fprintf(w, "  [%d]\tweight %g\t%.1f%% of the step rate\n", i+1, ep.Weight, share*100)
+	}
+
+	var steps []int
+	start, maxRPS, step := lt.SearchStartRPS(), lt.Search.MaxRPS, lt.SearchStepDuration()
+	path := "if every step passes"
+	if searchStrategy(lt) == config.SearchBinary {
+		// The largest search fails at max_rps, or at the start rate and
+		// bisects below it, and passes every step of the bisection after
+		// that, which keeps it in the upper half.
+		path = "largest search, max_rps failing"
+		steps = []int{start}
+		if start < maxRPS {
+			steps = append(steps, maxRPS)
+			steps = append(steps, bisectSteps(start, maxRPS, lt.SearchPrecision())...)
+		}
+		below := append([]int{start}, bisectSteps(0, start, lt.SearchPrecision())...)
+		if searchRequests(lt, below) > searchRequests(lt, steps) {
+			path = "largest search, start_rps failing"
+			steps = below
+		}
+	} else {
+		for rps := start; rps <= maxRPS; rps += lt.SearchStep() {
+			steps = append(steps, rps)
+		}
+	}
+
+	fmt.Fprintf(w, "\nRate schedule (%s, %s):\n", searchStrategy(lt), path)
+	warmup := writeWarmupSchedule(w, lt)
+	total := 0.0
+	for i, rps := range steps {
+		rate := expectedRPS(lt, rps)
+		start := lt.Warmup.Duration + i*step
+		fmt.Fprintf(w, "  %ds - %ds\t%g RPS\n", start, start+step, rate)
+		total += rate * float64(step)
+	}
+	expected := fmt.Sprintf("up to %.0f", total)
+	if warmup > 0 {
+		expected += fmt.Sprintf(" + %.0f warm-up", warmup)
+	}
+	fmt.Fprintf(w, "\nExpected requests: %s\n", expected)
+}
+
+// bisectSteps returns the rates a binary search between a passing lo and a
+// failing hi tries when every step passes.
+func bisectSteps(lo, hi, precision int) []int {
+	var steps []int
+	for hi-lo > precision {
+		lo = lo + (hi-lo)/2
+		steps = append(steps, lo)
+	}
+	return steps
+}
+
+// searchRequests returns the relative number of requests of search steps
+// of equal duration.
+func searchRequests(lt config.LoadTestConfig, steps []int) float64 {
+	total := 0.0
+	for _, rps := range steps {
+		total += expectedRPS(lt, rps)
+	}
+	return total
+}
+
+// writeWarmupSchedule writes the warm-up line of the rate schedule and
+// returns the number of warm-up requests, 0 when the run has no warm-up.
+func writeWarmupSchedule(w io.Writer, lt config.LoadTestConfig) float64 {
+	if lt.Warmup.Duration == 0 {
+		return 0
+	}
+	rate := expectedRPS(lt, lt.WarmupRPS())
+	measured := "excluded from the statistics"
+	if lt.Warmup.Include {
+		measured = "included in the statistics"
+	}
+	fmt.Fprintf(w, "  0s - %ds\t%g RPS (warm-up, %s)\n", lt.Warmup.Duration, rate, measured)
+	return rate * float64(lt.Warmup.Duration)
+}
+
+// writeConnections estimates the connections a run opens, by protocol.
+// Requests over a kept-alive connection occupy it while they are in
+// flight, so at most one connection per worker that sends them is open.
+func (e *Engine) writeConnections(w io.Writer) {
+	lt := e.cfg.LoadTest
+	shares := endpointShares(lt)
+	rps := lt.RPS
+	if lt.RunMode() == config.ModeSearch {
+		rps = lt.Search.MaxRPS
+	}
+
+	type estimate struct {
+		workers int
+		shared  bool
+		rate    float64
+	}
+	groups := make(map[string]*estimate)
+	for i, ep := range lt.Endpoints {
+		group := ep.Protocol()
+		switch group {
+		case config.EndpointGraphQL, config.EndpointSSE:
+			group = config.EndpointHTTP
+		}
+		g, ok := groups[group]
+		if !ok {
+			g = &estimate{}
+			groups[group] = g
+		}
+		if ep.Concurrency > 0 {
+			g.workers += ep.Concurrency
+		} else if !g.shared {
+			g.shared = true
+			g.workers += lt.Concurrency
+		}
+		if ep.RPS > 0 {
+			g.rate += ep.RPS
+		} else {
+			g.rate += shares[i] * float64(rps)
+		}
+	}
+
+	fmt.Fprintf(w, "\nConnections (estimated):\n")
+	for _, group := range []string{config.EndpointHTTP, config.EndpointGRPC, config.EndpointWebSocket, config.EndpointTCP, config.EndpointUDP, config.EndpointDNS} {
+		g, ok := groups[group]
+		if !ok {
+			continue
+		}
+		switch group {
+		case config.EndpointHTTP:
+			fmt.Fprintf(w, "  HTTP\tup to %d open, one per worker, kept alive between requests\n", g.workers)
+		case config.EndpointGRPC:
+			fmt.Fprintf(w, "  gRPC\t1, shared by all workers\n")
+		case config.EndpointWebSocket:
+			fmt.Fprintf(w, "  WebSocket\t%.2f new per second, up to %d open\n", g.rate, g.workers)
+		case config.EndpointTCP:
+			fmt.Fprintf(w, "  TCP\tup to %d open per address, kept alive between requests\n", g.workers)
+		case config.EndpointUDP:
+			fmt.Fprintf(w, "  UDP\t%.2f new sockets per second\n", g.rate)
+		case config.EndpointDNS:
+			fmt.Fprintf(w, "  DNS\t%.2f new sockets per second\n", g.rate)
+		}
+	}
+}
+
+// writeReplayDryRun writes the request log a replay run would send and its
+// timing.
+func (e *Engine) writeReplayDryRun(w io.Writer) {
+	lt := e.cfg.LoadTest
+	entries := e.entries
+	fmt.Fprintf(w, "Mode: %s\n", lt.RunMode())
+	fmt.Fprintf(w, "Domain: %s\n", e.cfg.Secrets.Mask(lt.Domain))
+	fmt.Fprintf(w, "Request log: %s (%d requests)\n", lt.Replay.File, len(entries))
+
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[entry.Method+" "+entry.Path]++
+	}
+	fmt.Fprintf(w, "\nRequest mix:\n")
+	for _, request := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "  %s\t%.1f%%\n", e.cfg.Secrets.Mask(request), percent(counts[request], len(entries)))
+	}
+
+	fmt.Fprintf(w, "\nRate schedule:\n")
+	expected := len(entries)
+	if lt.Replay.Timing == config.TimingRPS {
+		fmt.Fprintf(w, "  0s - %ds\t%d RPS\n", lt.Duration, lt.RPS)
+		expected = lt.RPS * lt.Duration
+	} else {
+		speed := replaySpeed(lt)
+		span := time.Duration(float64(entries[len(entries)-1].Offset) / speed)
+		fmt.Fprintf(w, "  0s - %s\toriginal timing at %gx speed\n", span.Round(time.Millisecond), speed)
+	}
+	fmt.Fprintf(w, "\nExpected requests: %d\n", expected)
+	fmt.Fprintf(w, "\nConnections (estimated):\n")
+	fmt.Fprintf(w, "  HTTP\tup to %d open, one per worker, kept alive between requests\n", lt.Concurrency)
+}
+
+// validate sends one request to every endpoint, one after the other, and
+// writes their outcome to w.
+func (e *Engine) validate(ctx context.Context, w io.Writer, seed int64) error {
+	lt := e.cfg.LoadTest
+	executors, err := e.newExecutors(ctx, lt.Endpoints)
+	if err != nil {
+		return err
+	}
+	defer closeExecutors(executors)
+
+	e.resetFeeders()
+	s := e.newLoadSchedule(newRand(seed), lt.RPS, lt.Duration)
+	auth := e.newAuthenticator(ctx, func(report.RequestResult) {})
+	defer auth.close()
+
+	fmt.Fprintf(w, "\nValidation:\n")
+	failed := 0
+	urls := endpointURLs(lt)
+	for i, ep := range lt.Endpoints {
+		var results []report.RequestResult
+		work := make(chan target, 1)
+		t := s.prepare(target{url: urls[i], reportURL: urls[i], index: i, endpoint: ep})
+		t.end = time.Now().Add(time.Duration(lt.Duration) * time.Second)
+		work <- t
+		close(work)
+		e.runWorkers(ctx, executors, auth, 1, 0, work, func(result report.RequestResult) {
+			results = append(results, result)
+		}).Wait()
+
+		method, path := describeEndpoint(lt, ep)
+		ok := len(results) > 0
+		for _, result := range results {
+			ok = ok && result.Error == ""
+		}
+		if !ok {
+			failed++
+		}
+		fmt.Fprintf(w, "  [%d] %s %s: %s\n", i+1, method, e.cfg.Secrets.Mask(path), describeValidation(results, e.cfg.Secrets.Mask))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d endpoints failed validation", failed, len(lt.Endpoints))
+	}
+	return nil
+}
+
+// describeValidation summarizes the results of one validation request.
+// Connection-oriented endpoints record one result per phase; the first
+// failing one is shown.
+func describeValidation(results []report.RequestResult, mask func(string) string) string {
+	if len(results) == 0 {
+		return "FAIL (no response)"
+	}
+	var elapsed time.Duration
+	for _, result := range results {
+		elapsed += result.Duration
+		if result.Error != "" {
+			return fmt.Sprintf("FAIL (%s) %s", result.ErrorClass, mask(result.Error))
+		}
+	}
+	if code := results[0].StatusCode; code != 0 {
+		return fmt.Sprintf("OK %d in %s", code, elapsed.Round(time.Microsecond))
+	}
+	return fmt.Sprintf("OK in %s", elapsed.Round(time.Microsecond))
+}
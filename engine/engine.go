@@ -0,0 +1,202 @@
+// Package engine runs meteor-shower load tests in-process. It is the same
+// engine the meteor-shower CLI uses, for driving load tests from Go code
+// such as integration tests:
+//
+//	cfg := config.Default()
+//	cfg.LoadTest.Domain = server.URL
+//	eng, err := engine.New(cfg, engine.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	results, err := eng.Run(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	stats := results.CalculateStatistics()
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/internal/replay"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// DefaultTimeout is the request timeout used when Options.Timeout is zero.
+const DefaultTimeout = 10 * time.Second
+
+// Options customizes an Engine. The zero value is ready to use.
+type Options struct {
+	// Transport sends the requests. http.DefaultTransport is used when nil.
+	Transport http.RoundTripper
+	// Timeout limits each request including reading its response body.
+	// DefaultTimeout is used when zero.
+	Timeout time.Duration
+	// Log receives the test plan and progress messages. Nothing is logged
+	// when nil.
+	Log io.Writer
+	// Hooks are called while the test runs.
+	Hooks Hooks
+}
+
+// Hooks are callbacks invoked during a run. Every hook is optional.
+type Hooks struct {
+	// OnStart is called before the first request is sent.
+	OnStart func()
+	// OnResult is called after every request with its outcome. Calls are
+	// serialized, so the hook needs no locking of its own.
+	OnResult func(report.RequestResult)
+	// OnFinish is called with the results once the run has ended.
+	OnFinish func(*report.Results)
+}
+
+// Engine runs the load test described by a configuration. An Engine can be
+// run several times; each run starts from scratch.
+type Engine struct {
+	cfg     config.Config
+	opts    Options
+	client  *http.Client
+	entries []replay.Entry
+	// templates are the parsed request templates, nil when no endpoint
+	// uses them.
+	templates *template.Template
+	feeders   map[string]*feeder
+	// feederUses lists the feeders the templates of every endpoint read.
+	feederUses [][]string
+	cookies    []seedCookie
+}
+
+// New validates cfg and returns an engine for it. In replay mode the request
+// log is read here, and with checkpoints enabled their directory is created
+// here, so that a missing or malformed log or an unwritable directory is
+// reported before the run.
+func New(cfg *config.Config, opts Options) (*Engine, error) {
+	if issues := cfg.Validate(); len(issues) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", config.JoinIssues(issues))
+	}
+
+	e := &Engine{cfg: *cfg, opts: opts}
+
+	// Normalize endpoint weights (set default to 1.0 if not specified)
+	e.cfg.LoadTest.Endpoints = append([]config.Endpoint(nil), cfg.LoadTest.Endpoints...)
+	for i := range e.cfg.LoadTest.Endpoints {
+		if e.cfg.LoadTest.Endpoints[i].Weight <= 0 {
+			e.cfg.LoadTest.Endpoints[i].Weight = 1.0
+		}
+	}
+
+	if e.opts.Log == nil {
+		e.opts.Log = io.Discard
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	e.client = &http.Client{
+		Transport: opts.Transport,
+		Timeout:   timeout,
+	}
+
+	templates, feeders, uses, err := compileTemplates(e.cfg.LoadTest)
+	if err != nil {
+		return nil, err
+	}
+	e.templates, e.feeders, e.feederUses = templates, feeders, uses
+	if e.cookies, err = newSeedCookies(e.cfg.LoadTest); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoadTest.RunMode() == config.ModeReplay {
+		entries, err := replay.Load(cfg.LoadTest.Replay.File, cfg.LoadTest.Replay.Format)
+		if err != nil {
+			return nil, err
+		}
+		e.entries = entries
+	}
+	if cfg.LoadTest.Checkpoint.Interval > 0 {
+		if err := os.MkdirAll(cfg.LoadTest.Checkpoint.Directory(), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// Run executes the load test and returns its results. When ctx is canceled
+// no further requests are sent, in-flight requests are aborted, and the
+// results collected so far are returned together with ctx.Err(). With
+// checkpoints enabled the results carry an Aggregate instead of the
+// individual requests, which are in the raw result files. An error
+// without results means the run could not start, e.g. because the messages
+// of a gRPC endpoint could not be resolved.
+func (e *Engine) Run(ctx context.Context) (*report.Results, error) {
+	// Replayed requests are always HTTP.
+	var endpoints []config.Endpoint
+	if e.cfg.LoadTest.RunMode() != config.ModeReplay {
+		endpoints = e.cfg.LoadTest.Endpoints
+	}
+	executors, err := e.newExecutors(ctx, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	defer closeExecutors(executors)
+
+	e.resetFeeders()
+	var results *report.Results
+	switch e.cfg.LoadTest.RunMode() {
+	case config.ModeReplay:
+		e.printReplayPlan()
+		results = e.runReplay(ctx, executors)
+	case config.ModeSearch:
+		seed := e.seed()
+		e.printSearchPlan(seed)
+		results = e.runSearch(ctx, executors, seed)
+	default:
+		seed := e.seed()
+		e.printLoadPlan(seed)
+		results = e.runLoad(ctx, executors, seed)
+	}
+
+	if e.opts.Hooks.OnFinish != nil {
+		e.opts.Hooks.OnFinish(results)
+	}
+	return results, ctx.Err()
+}
+
+// recorder returns a function that appends a result to results and calls
+// the OnResult hook, safe for use from several workers.
+func (e *Engine) recorder(results *report.Results) func(report.RequestResult) {
+	var mu sync.Mutex
+	return func(result report.RequestResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results.Requests = append(results.Requests, result)
+		if e.opts.Hooks.OnResult != nil {
+			e.opts.Hooks.OnResult(result)
+		}
+	}
+}
+
+// tokenRecorder returns a function that appends a token request to
+// results, safe for concurrent use.
+func (e *Engine) tokenRecorder(results *report.Results) func(report.RequestResult) {
+	var mu sync.Mutex
+	return func(result report.RequestResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results.TokenFetches = append(results.TokenFetches, result)
+	}
+}
+
+func (e *Engine) start() {
+	if e.opts.Hooks.OnStart != nil {
+		e.opts.Hooks.OnStart()
+	}
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// graphqlExecutor posts GraphQL operations and checks the errors field of
+// the response in addition to the HTTP status.
+type graphqlExecutor struct {
+	client *http.Client
+}
+
+// graphqlRequest is the standard JSON body of a GraphQL request over HTTP.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+func (x *graphqlExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	gql := t.endpoint.GraphQL
+	start := time.Now()
+	result := report.RequestResult{
+		Timestamp: start,
+		URL:       t.reportURL,
+		Protocol:  report.ProtocolGraphQL,
+		Operation: gql.Operation(),
+	}
+
+	resp, err := x.do(ctx, t)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseHeader)
+		record(result)
+		return
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	data, err := io.ReadAll(resp.Body)
+	result.Duration = time.Since(start)
+	switch {
+	case err != nil:
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseBody)
+	case resp.StatusCode >= 400:
+		result.ErrorClass, result.Error = report.ClassifyStatus(resp.StatusCode)
+	default:
+		result.ErrorClass, result.Error = report.ClassifyGraphQLResponse(data)
+	}
+	if result.ErrorClass == "" {
+		result.ErrorClass, result.Error = checkResponse(t, data)
+	}
+	record(result)
+}
+
+// do posts the operation of t as JSON. The endpoint method and headers
+// apply; the body is replaced by the operation.
+func (x *graphqlExecutor) do(ctx context.Context, t target) (*http.Response, error) {
+	payload, err := json.Marshal(graphqlRequest{
+		Query:         t.endpoint.GraphQL.Query,
+		OperationName: t.endpoint.GraphQL.OperationName,
+		Variables:     t.endpoint.GraphQL.Variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := t.endpoint
+	endpoint.Body = string(payload)
+	if endpoint.Method == "" {
+		endpoint.Method = http.MethodPost
+	}
+	req, err := newRequest(ctx, endpoint, t.url)
+	if err != nil {
+		return nil, err
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	return withJar(x.client, t.jar).Do(req)
+}
+
+func (x *graphqlExecutor) close() error {
+	return nil
+}
@@ -0,0 +1,363 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// grpcExecutor calls gRPC methods whose request and response messages are
+// built at runtime from descriptors, so no generated code is needed.
+type grpcExecutor struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+	methods map[string]*grpcMethod
+	// byPath holds a resolved method per method name, for endpoints whose
+	// body is rendered from a template and so is only known per request.
+	byPath map[string]*grpcMethod
+}
+
+// grpcMethod is a resolved gRPC endpoint. requests is nil when the body is
+// a template.
+type grpcMethod struct {
+	name     string
+	desc     protoreflect.MethodDescriptor
+	requests []proto.Message
+}
+
+// newGRPCExecutor connects to the host of domain, using TLS for https, and
+// resolves the method of every endpoint from its descriptor set or, when
+// none is given, from the server reflection service.
+func newGRPCExecutor(ctx context.Context, domain string, endpoints []config.Endpoint, timeout time.Duration) (*grpcExecutor, error) {
+	u, err := url.Parse(domain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain %q: %w", domain, err)
+	}
+	creds := insecure.NewCredentials()
+	if u.Scheme == "https" {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gRPC server %s: %w", u.Host, err)
+	}
+
+	x := &grpcExecutor{conn: conn, timeout: timeout, methods: make(map[string]*grpcMethod), byPath: make(map[string]*grpcMethod)}
+	resolvers := make(map[string]*protoregistry.Files)
+	for _, ep := range endpoints {
+		key := grpcMethodKey(ep)
+		if _, ok := x.methods[key]; ok {
+			continue
+		}
+		method, err := x.resolve(ctx, ep, resolvers)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("gRPC endpoint %s: %w", ep.Path, err)
+		}
+		x.methods[key] = method
+		x.byPath[ep.Path] = method
+	}
+	return x, nil
+}
+
+// grpcMethodKey identifies the resolved method of an endpoint. Endpoints
+// calling the same method with different messages are resolved
+// separately.
+func grpcMethodKey(ep config.Endpoint) string {
+	return ep.Path + "\x00" + ep.Body
+}
+
+func (x *grpcExecutor) resolve(ctx context.Context, ep config.Endpoint, resolvers map[string]*protoregistry.Files) (*grpcMethod, error) {
+	service, methodName, _ := strings.Cut(ep.Path[1:], "/")
+
+	// Descriptor sets are loaded once per file, reflection once per service.
+	key := "reflection:" + service
+	if ep.GRPC.DescriptorSet != "" {
+		key = "file:" + ep.GRPC.DescriptorSet
+	}
+	files, ok := resolvers[key]
+	if !ok {
+		var err error
+		if ep.GRPC.DescriptorSet != "" {
+			files, err = loadDescriptorSet(ep.GRPC.DescriptorSet)
+		} else {
+			files, err = x.reflectFiles(ctx, service)
+		}
+		if err != nil {
+			return nil, err
+		}
+		resolvers[key] = files
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, methodName)
+	}
+
+	m := &grpcMethod{
+		name: "/" + service + "/" + methodName,
+		desc: md,
+	}
+	// Templated bodies are parsed per request, once rendered.
+	if !strings.Contains(ep.Body, "{{") {
+		if m.requests, err = parseGRPCRequests(ep.Body, md); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseGRPCRequests decodes body into request messages. Client streaming
+// methods take a JSON array of messages; every other method takes a single
+// message. An empty body is an empty message.
+func parseGRPCRequests(body string, md protoreflect.MethodDescriptor) ([]proto.Message, error) {
+	docs := []json.RawMessage{json.RawMessage("{}")}
+	if body = strings.TrimSpace(body); body != "" {
+		docs = []json.RawMessage{json.RawMessage(body)}
+		if md.IsStreamingClient() && strings.HasPrefix(body, "[") {
+			docs = nil
+			if err := json.Unmarshal([]byte(body), &docs); err != nil {
+				return nil, fmt.Errorf("invalid request messages: %w", err)
+			}
+		}
+	}
+
+	requests := make([]proto.Message, len(docs))
+	for i, doc := range docs {
+		msg := dynamicpb.NewMessage(md.Input())
+		if err := protojson.Unmarshal(doc, msg); err != nil {
+			return nil, fmt.Errorf("invalid %s message: %w", md.Input().FullName(), err)
+		}
+		requests[i] = msg
+	}
+	return requests, nil
+}
+
+// loadDescriptorSet reads a FileDescriptorSet as written by
+// protoc --descriptor_set_out --include_imports.
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+	}
+	return buildFiles(set.File)
+}
+
+// reflectFiles fetches the file defining service, and the files it
+// imports, from the server reflection service.
+func (x *grpcExecutor) reflectFiles(ctx context.Context, service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	stream, err := reflectionpb.NewServerReflectionClient(x.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection failed: %w", err)
+	}
+	defer stream.CloseSend()
+
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	var order []*descriptorpb.FileDescriptorProto
+	fetch := func(req *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("server reflection failed: %w", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("server reflection failed: %w", err)
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return fmt.Errorf("server reflection failed: %s", errResp.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return fmt.Errorf("invalid file descriptor from server reflection: %w", err)
+			}
+			if _, ok := files[fd.GetName()]; !ok {
+				files[fd.GetName()] = fd
+				order = append(order, fd)
+			}
+		}
+		return nil
+	}
+
+	if err := fetch(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	}); err != nil {
+		return nil, err
+	}
+	// Servers usually send the transitive imports along; fetch any that
+	// are missing and not linked into this binary.
+	for i := 0; i < len(order); i++ {
+		for _, dep := range order[i].GetDependency() {
+			if _, ok := files[dep]; ok {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				continue
+			}
+			if err := fetch(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buildFiles(order)
+}
+
+// buildFiles links file descriptors into a registry. Imports missing from
+// fds, such as the well-known types, are taken from the descriptors linked
+// into this binary.
+func buildFiles(fds []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{File: fds}
+	present := make(map[string]bool, len(fds))
+	for _, fd := range fds {
+		present[fd.GetName()] = true
+	}
+	for i := 0; i < len(set.File); i++ {
+		for _, dep := range set.File[i].GetDependency() {
+			if present[dep] {
+				continue
+			}
+			if global, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				set.File = append(set.File, protodesc.ToFileDescriptorProto(global))
+				present[dep] = true
+			}
+		}
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptors: %w", err)
+	}
+	return files, nil
+}
+
+func (x *grpcExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	var requests []proto.Message
+	m, ok := x.methods[grpcMethodKey(t.endpoint)]
+	if ok {
+		requests = m.requests
+	} else {
+		// The body was rendered from a template.
+		var err error
+		if m, ok = x.byPath[t.endpoint.Path]; !ok {
+			err = fmt.Errorf("gRPC method %s was not resolved", t.endpoint.Path)
+		} else {
+			requests, err = parseGRPCRequests(t.endpoint.Body, m.desc)
+		}
+		if err != nil {
+			record(report.RequestResult{
+				Timestamp:  time.Now(),
+				URL:        t.reportURL,
+				Protocol:   report.ProtocolGRPC,
+				Error:      err.Error(),
+				ErrorClass: report.ErrorClassOther,
+			})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+	md := metadata.MD{}
+	for name, value := range t.endpoint.Headers {
+		md.Append(strings.ToLower(name), value)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	start := time.Now()
+	var err error
+	if !m.desc.IsStreamingClient() && !m.desc.IsStreamingServer() {
+		err = x.conn.Invoke(ctx, m.name, requests[0], dynamicpb.NewMessage(m.desc.Output()))
+	} else {
+		err = x.stream(ctx, m, requests)
+	}
+	elapsed := time.Since(start)
+
+	st := status.Convert(err)
+	result := report.RequestResult{
+		Timestamp:  start,
+		Duration:   elapsed,
+		StatusCode: int(st.Code()),
+		URL:        t.reportURL,
+		Protocol:   report.ProtocolGRPC,
+	}
+	result.ErrorClass, result.Error = report.ClassifyGRPCStatus(int(st.Code()), st.Message())
+	record(result)
+}
+
+// stream sends every request message, closes the send direction and reads
+// responses until the server ends the stream.
+func (x *grpcExecutor) stream(ctx context.Context, m *grpcMethod, requests []proto.Message) error {
+	desc := &grpc.StreamDesc{
+		StreamName:    string(m.desc.Name()),
+		ClientStreams: m.desc.IsStreamingClient(),
+		ServerStreams: m.desc.IsStreamingServer(),
+	}
+	stream, err := x.conn.NewStream(ctx, desc, m.name)
+	if err != nil {
+		return err
+	}
+	for _, req := range requests {
+		if err := stream.SendMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The server ended the stream; RecvMsg returns its status.
+				break
+			}
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		if err := stream.RecvMsg(dynamicpb.NewMessage(m.desc.Output())); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (x *grpcExecutor) close() error {
+	return x.conn.Close()
+}
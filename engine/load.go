@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+func (e *Engine) printLoadPlan(seed int64) {
+	lt := e.cfg.LoadTest
+	mask := e.cfg.Secrets.Mask
+	fmt.Fprintf(e.opts.Log, "Starting load test...\n")
+	fmt.Fprintf(e.opts.Log, "Domain: %s\n", mask(lt.Domain))
+	fmt.Fprintf(e.opts.Log, "Endpoints: %d\n", len(lt.Endpoints))
+	for i, ep := range lt.Endpoints {
+		method, path := describeEndpoint(lt, ep)
+		share := fmt.Sprintf("weight: %.2f", ep.Weight)
+		if ep.RPS > 0 {
+			share = fmt.Sprintf("rps: %g", ep.RPS)
+		}
+		if ep.Concurrency > 0 {
+			share += fmt.Sprintf(", concurrency: %d", ep.Concurrency)
+		}
+		fmt.Fprintf(e.opts.Log, "  [%d] %s %s (%s)\n", i+1, method, mask(path), share)
+	}
+	if fixed := lt.FixedRPS(); fixed > 0 {
+		fmt.Fprintf(e.opts.Log, "RPS: %d weighted + %g fixed\n", lt.RPS, fixed)
+	} else {
+		fmt.Fprintf(e.opts.Log, "RPS: %d\n", lt.RPS)
+	}
+	fmt.Fprintf(e.opts.Log, "Concurrency: %d\n", lt.Concurrency)
+	fmt.Fprintf(e.opts.Log, "Duration: %ds\n", lt.Duration)
+	if warmup := describeWarmup(lt); warmup != "" {
+		fmt.Fprintf(e.opts.Log, "%s\n", warmup)
+	}
+	fmt.Fprintf(e.opts.Log, "Seed: %d\n", seed)
+	fmt.Fprintf(e.opts.Log, "\n")
+}
+
+// describeEndpoint returns the method, or the protocol for endpoints
+// without one, and the path the test plan shows for ep.
+func describeEndpoint(lt config.LoadTestConfig, ep config.Endpoint) (string, string) {
+	method := ep.RequestMethod()
+	if ep.Protocol() != config.EndpointHTTP {
+		method = strings.ToUpper(ep.Protocol())
+	}
+	path := ep.Path
+	switch ep.Protocol() {
+	case config.EndpointGraphQL:
+		path += " " + ep.GraphQL.Operation()
+	case config.EndpointDNS, config.EndpointTCP, config.EndpointUDP:
+		path = ep.URL(lt.Domain)
+	}
+	return method, path
+}
+
+// runLoad sends requests to the weighted endpoints at the configured rate
+// for the configured duration, making its random choices with seed.
+func (e *Engine) runLoad(ctx context.Context, executors map[string]executor, seed int64) *report.Results {
+	lt := e.cfg.LoadTest
+	results := &report.Results{
+		URLs:        endpointURLs(lt),
+		RPS:         int(math.Round(expectedRPS(lt, lt.RPS))),
+		Concurrency: lt.Concurrency,
+		Duration:    lt.Duration,
+		StartTime:   time.Now(),
+		Requests:    make([]report.RequestResult, 0),
+		Seed:        seed,
+	}
+
+	record, recordToken := e.recorder(results), e.tokenRecorder(results)
+	var cp *checkpointer
+	if lt.Checkpoint.Interval > 0 {
+		cp = e.newCheckpointer(results)
+		record, recordToken = cp.record, cp.recordToken
+	}
+
+	// The error stop conditions abort the run through its own context, so
+	// that an abort is not mistaken for an interruption by the caller.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := newStopper(lt.Stop, cancel)
+	record = stop.wrap(record)
+
+	auth := e.newAuthenticator(runCtx, recordToken)
+	e.start()
+	if lt.Warmup.Duration > 0 {
+		results.Warmup = e.runWarmup(runCtx, executors, auth, seed)
+		results.StartTime = time.Now()
+	}
+	if cp != nil {
+		cp.start()
+	}
+	condition, reason := e.sendLoad(runCtx, executors, auth, newRand(results.Seed), lt.RPS, lt.Duration, lt.Stop, record)
+	end := time.Now()
+	auth.close()
+	if cp != nil {
+		// The checkpointer copies the results until it has finished.
+		cp.finish(end)
+	}
+	results.EndTime = end
+
+	if c, r := stop.stopped(); c != "" {
+		condition, reason = c, r
+	} else if ctx.Err() != nil {
+		condition, reason = report.StopInterrupted, "interrupted"
+	}
+	if condition == "" {
+		condition, reason = report.StopDuration, fmt.Sprintf("ran for the duration of %ds", lt.Duration)
+	}
+	results.StopCondition, results.StopReason = condition, reason
+	if condition != report.StopDuration {
+		fmt.Fprintf(e.opts.Log, "Stopped early: %s\n", reason)
+	}
+	return results
+}
+
+// expectedRPS returns the total rate sendLoad sends at when the weighted
+// endpoints get rps.
+func expectedRPS(lt config.LoadTestConfig, rps int) float64 {
+	total := lt.FixedRPS()
+	for _, ep := range lt.Endpoints {
+		if ep.RPS == 0 {
+			return total + float64(rps)
+		}
+	}
+	return total
+}
+
+// endpointURLs returns the URL of every endpoint.
+func endpointURLs(lt config.LoadTestConfig) []string {
+	urls := make([]string, 0, len(lt.Endpoints))
+	for _, ep := range lt.Endpoints {
+		urls = append(urls, ep.URL(lt.Domain))
+	}
+	return urls
+}
+
+// sendLoad sends requests to the weighted endpoints at rps and to the
+// endpoints with a rate of their own at that rate, for duration seconds,
+// and passes their outcome to record. Random choices are drawn from rng.
+// The request count and iteration limits of stop apply. It returns once
+// all requests have completed, with the stop condition that ended the run
+// early, if any.
+func (e *Engine) sendLoad(ctx context.Context, executors map[string]executor, auth *authenticator, rng *rand.Rand, rps, duration int, stop config.StopConfig, record func(report.RequestResult)) (condition, reason string) {
+	lt := e.cfg.LoadTest
+	schedule := e.newLoadSchedule(rng, rps, duration)
+
+	// Channels to distribute work. Endpoints with a concurrency cap have
+	// workers of their own; all others share the configured workers.
+	queued := make([]int, len(lt.Endpoints))
+	shared := 0
+	for _, st := range schedule.streams {
+		switch {
+		case st.target == nil:
+			shared += st.total
+			for _, wt := range schedule.weighted {
+				queued[wt.index] = st.total
+			}
+		case st.target.endpoint.Concurrency == 0:
+			shared += st.total
+		default:
+			queued[st.target.index] = st.total
+		}
+	}
+	workChan := make(chan target, shared)
+	queues := make(map[int]chan target)
+	for i, ep := range lt.Endpoints {
+		if ep.Concurrency > 0 {
+			queues[i] = make(chan target, queued[i])
+		}
+	}
+	queue := func(t target) chan target {
+		if q, ok := queues[t.index]; ok {
+			return q
+		}
+		return workChan
+	}
+
+	// Start workers. Workers only finish before their queue is closed when
+	// they have run all their iterations.
+	workers := []*sync.WaitGroup{e.runWorkers(ctx, executors, auth, lt.Concurrency, stop.Iterations, workChan, record)}
+	for i, q := range queues {
+		workers = append(workers, e.runWorkers(ctx, executors, auth, lt.Endpoints[i].Concurrency, stop.Iterations, q, record))
+	}
+	workersDone := make(chan struct{})
+	go func() {
+		for _, wg := range workers {
+			wg.Wait()
+		}
+		close(workersDone)
+	}()
+
+	// Send every request when it is due.
+	start := time.Now()
+	sent := 0
+loop:
+	for {
+		offset, t, ok := schedule.next()
+		if !ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-workersDone:
+			break loop
+		case <-time.After(time.Until(start.Add(offset))):
+		}
+		t.end = start.Add(time.Duration(duration) * time.Second)
+		queue(t) <- t
+		sent++
+		if sent == stop.MaxRequests {
+			condition, reason = report.StopMaxRequests, fmt.Sprintf("sent max_requests of %d", sent)
+			break
+		}
+	}
+	if condition == "" {
+		select {
+		case <-workersDone:
+			condition, reason = report.StopIterations, fmt.Sprintf("every worker ran %d iterations", stop.Iterations)
+		default:
+		}
+	}
+
+	close(workChan)
+	for _, q := range queues {
+		close(q)
+	}
+	<-workersDone
+	return condition, reason
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/internal/replay"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// runReplay reissues the requests of a request log against the configured
+// domain. With original timing every request is sent at its logged offset
+// divided by the speed factor; with rps timing the logged requests are sent
+// in order, wrapping around, at the configured rate for the configured
+// duration.
+func (e *Engine) runReplay(ctx context.Context, executors map[string]executor) *report.Results {
+	lt := e.cfg.LoadTest
+	entries := e.entries
+	results := &report.Results{
+		URLs:        []string{lt.Domain},
+		RPS:         lt.RPS,
+		Concurrency: lt.Concurrency,
+		Duration:    lt.Duration,
+		StartTime:   time.Now(),
+		Requests:    make([]report.RequestResult, 0, len(entries)),
+	}
+
+	// The channel holds every request of the replay, so that a slow target
+	// cannot hold up the schedule.
+	total := len(entries)
+	if lt.Replay.Timing == config.TimingRPS {
+		total = lt.RPS * lt.Duration
+	}
+	workChan := make(chan target, total)
+	e.start()
+	auth := e.newAuthenticator(ctx, e.tokenRecorder(results))
+	defer auth.close()
+	wg := e.runWorkers(ctx, executors, auth, lt.Concurrency, lt.Stop.Iterations, workChan, e.recorder(results))
+
+	toTarget := func(e replay.Entry) target {
+		return target{
+			url:       lt.Domain + e.Path,
+			reportURL: lt.Domain + e.Path,
+			index:     -1,
+			endpoint: config.Endpoint{
+				Method:  e.Method,
+				Path:    e.Path,
+				Headers: e.Headers,
+				Body:    e.Body,
+			},
+		}
+	}
+
+	if lt.Replay.Timing == config.TimingRPS {
+		ticker := time.NewTicker(time.Second / time.Duration(lt.RPS))
+		timeout := time.After(time.Duration(lt.Duration) * time.Second)
+	loop:
+		for i := 0; i < total; i++ {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-timeout:
+				break loop
+			case <-ticker.C:
+				workChan <- toTarget(entries[i%len(entries)])
+			}
+		}
+		ticker.Stop()
+	} else {
+		speed := replaySpeed(lt)
+		start := time.Now()
+	replayLoop:
+		for _, entry := range entries {
+			due := start.Add(time.Duration(float64(entry.Offset) / speed))
+			select {
+			case <-ctx.Done():
+				break replayLoop
+			case <-time.After(time.Until(due)):
+			}
+			workChan <- toTarget(entry)
+		}
+
+		span := time.Duration(float64(entries[len(entries)-1].Offset) / speed)
+		results.Duration = int(span.Round(time.Second) / time.Second)
+		if span > 0 {
+			results.RPS = int(float64(len(entries)) / span.Seconds())
+		}
+	}
+
+	close(workChan)
+	wg.Wait()
+	results.EndTime = time.Now()
+	return results
+}
+
+func (e *Engine) printReplayPlan() {
+	lt := e.cfg.LoadTest
+	entries := e.entries
+	fmt.Fprintf(e.opts.Log, "Starting replay...\n")
+	fmt.Fprintf(e.opts.Log, "Domain: %s\n", e.cfg.Secrets.Mask(lt.Domain))
+	fmt.Fprintf(e.opts.Log, "Request log: %s (%d requests)\n", lt.Replay.File, len(entries))
+	if lt.Replay.Timing == config.TimingRPS {
+		fmt.Fprintf(e.opts.Log, "Timing: request mix at %d RPS for %ds\n", lt.RPS, lt.Duration)
+	} else {
+		speed := replaySpeed(lt)
+		span := time.Duration(float64(entries[len(entries)-1].Offset) / speed)
+		fmt.Fprintf(e.opts.Log, "Timing: original at %gx speed (%s)\n", speed, span.Round(time.Millisecond))
+	}
+	fmt.Fprintf(e.opts.Log, "Concurrency: %d\n", lt.Concurrency)
+	fmt.Fprintf(e.opts.Log, "\n")
+}
+
+func replaySpeed(lt config.LoadTestConfig) float64 {
+	if lt.Replay.Speed <= 0 {
+		return 1
+	}
+	return lt.Replay.Speed
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// loadSchedule yields the requests of a load run in the order they are
+// due: the weighted endpoints share one rate and every endpoint with a rate
+// of its own has another. All random choices are drawn from one generator
+// in that order, so a schedule with a given seed is the same on every run,
+// whichever worker ends up sending a request.
+type loadSchedule struct {
+	rng      *rand.Rand
+	weighted []weightedTarget
+	streams  []rateStream
+	feeders  map[string]*feeder
+	uses     [][]string
+}
+
+type weightedTarget struct {
+	target
+	weight float64
+}
+
+// rateStream is the requests sent at one rate.
+type rateStream struct {
+	interval    time.Duration
+	total, sent int
+	// target is the endpoint of a fixed rate; nil for the weighted
+	// endpoints.
+	target *target
+}
+
+// due returns the offset of the next request of the stream. The first
+// request is due one interval after the start.
+func (s *rateStream) due() time.Duration {
+	return time.Duration(s.sent+1) * s.interval
+}
+
+// fixedRateInterval returns the time between the requests of an endpoint
+// with a rate of its own.
+func fixedRateInterval(rps float64) time.Duration {
+	return time.Duration(float64(time.Second) / rps)
+}
+
+// fixedRateRequests returns the number of requests an endpoint with a rate
+// of its own sends in duration seconds: one for every interval that ends
+// within the duration. Counting intervals rather than truncating
+// rps*duration keeps a rate such as 0.29 RPS from losing a request to
+// floating point error.
+func fixedRateRequests(rps float64, duration int) int {
+	return int(time.Duration(duration) * time.Second / fixedRateInterval(rps))
+}
+
+// newRand returns the random generator of a run with seed.
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(seed)))
+}
+
+// newLoadSchedule returns the schedule of sending the weighted endpoints at
+// rps and the others at their own rate for duration seconds.
+func (e *Engine) newLoadSchedule(rng *rand.Rand, rps, duration int) *loadSchedule {
+	lt := e.cfg.LoadTest
+	urls := endpointURLs(lt)
+	s := &loadSchedule{rng: rng, feeders: e.feeders, uses: e.feederUses}
+
+	totalWeight := 0.0
+	for i, ep := range lt.Endpoints {
+		t := target{url: urls[i], reportURL: urls[i], index: i, endpoint: ep}
+		if ep.RPS > 0 {
+			s.streams = append(s.streams, rateStream{
+				interval: fixedRateInterval(ep.RPS),
+				total:    fixedRateRequests(ep.RPS, duration),
+				target:   &t,
+			})
+			continue
+		}
+		s.weighted = append(s.weighted, weightedTarget{target: t, weight: ep.Weight})
+		totalWeight += ep.Weight
+	}
+	if len(s.weighted) > 0 {
+		// Normalize weights
+		for i := range s.weighted {
+			s.weighted[i].weight /= totalWeight
+		}
+		s.streams = append([]rateStream{{interval: time.Second / time.Duration(rps), total: rps * duration}}, s.streams...)
+	}
+	return s
+}
+
+// next returns the next request and its offset from the start of the run,
+// or false when all requests have been scheduled.
+func (s *loadSchedule) next() (time.Duration, target, bool) {
+	var stream *rateStream
+	for i := range s.streams {
+		st := &s.streams[i]
+		if st.sent < st.total && (stream == nil || st.due() < stream.due()) {
+			stream = st
+		}
+	}
+	if stream == nil {
+		return 0, target{}, false
+	}
+	offset := stream.due()
+	stream.sent++
+
+	var t target
+	if stream.target != nil {
+		t = *stream.target
+	} else {
+		t = s.selectTarget()
+	}
+	return offset, s.prepare(t), true
+}
+
+// prepare draws the template seed and feeder rows of t.
+func (s *loadSchedule) prepare(t target) target {
+	t.seed = s.rng.Uint64()
+	if uses := s.uses[t.index]; len(uses) > 0 {
+		t.rows = make(map[string][]string, len(uses))
+		for _, name := range uses {
+			t.rows[name] = s.feeders[name].pick(s.rng)
+		}
+	}
+	return t
+}
+
+// selectTarget picks a weighted endpoint.
+func (s *loadSchedule) selectTarget() target {
+	r := s.rng.Float64()
+	cumulative := 0.0
+	for _, wt := range s.weighted {
+		cumulative += wt.weight
+		if r <= cumulative {
+			return wt.target
+		}
+	}
+	return s.weighted[len(s.weighted)-1].target
+}
+
+// seed returns the configured seed, or a random one when none is
+// configured. Random seeds stay below 2^53, so that they survive JSON
+// readers that parse numbers as doubles.
+func (e *Engine) seed() int64 {
+	if e.cfg.LoadTest.Seed != nil {
+		return *e.cfg.LoadTest.Seed
+	}
+	return rand.Int64N(1 << 53)
+}
+
+// WritePlan writes the schedule of a load mode run to w without sending
+// anything: the offset, endpoint and rendered path of every request in the
+// order they are sent, limited by the max_requests stop condition. With a seed configured
+// the plan is the same every time and matches the requests of a run. The
+// warm-up is not part of the plan.
+func (e *Engine) WritePlan(w io.Writer) error {
+	lt := e.cfg.LoadTest
+	if lt.RunMode() != config.ModeLoad {
+		return fmt.Errorf("a plan is only available in load mode")
+	}
+	seed := e.seed()
+	e.resetFeeders()
+	s := e.newLoadSchedule(newRand(seed), lt.RPS, lt.Duration)
+	r := e.newRenderer()
+	fmt.Fprintf(w, "# seed: %d\n", seed)
+	for n := 1; lt.Stop.MaxRequests == 0 || n <= lt.Stop.MaxRequests; n++ {
+		offset, t, ok := s.next()
+		if !ok {
+			break
+		}
+		if r != nil {
+			// Paths are shown rendered; a template that fails is shown as
+			// written.
+			if rendered, err := r.render(t); err == nil {
+				t = rendered
+			}
+		}
+		method, path := describeEndpoint(lt, t.endpoint)
+		if _, err := fmt.Fprintf(w, "%12.6f [%d] %s %s\n", offset.Seconds(), t.index+1, method, e.cfg.Secrets.Mask(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetFeeders starts every sequential feeder at its first row again, so
+// that every run reads the same rows.
+func (e *Engine) resetFeeders() {
+	for _, f := range e.feeders {
+		f.next.Store(0)
+	}
+}
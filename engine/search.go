@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// runSearch looks for the highest rate at which the target meets the SLO.
+// Every step sends the weighted endpoint mix at one rate for the step
+// duration; the requests of all steps end up in the results.
+func (e *Engine) runSearch(ctx context.Context, executors map[string]executor, seed int64) *report.Results {
+	lt := e.cfg.LoadTest
+	search := &report.SearchResult{Strategy: searchStrategy(lt)}
+	results := &report.Results{
+		URLs:        endpointURLs(lt),
+		Concurrency: lt.Concurrency,
+		StartTime:   time.Now(),
+		Requests:    make([]report.RequestResult, 0),
+		Search:      search,
+		Seed:        seed,
+	}
+	rng := newRand(results.Seed)
+
+	auth := e.newAuthenticator(ctx, e.tokenRecorder(results))
+	defer auth.close()
+	e.start()
+	if lt.Warmup.Duration > 0 {
+		results.Warmup = e.runWarmup(ctx, executors, auth, seed)
+		results.StartTime = time.Now()
+	}
+
+	try := func(rps int) bool {
+		step := &report.Results{StartTime: time.Now()}
+		e.sendLoad(ctx, executors, auth, rng, rps, lt.SearchStepDuration(), lt.Stop, e.recorder(step))
+		step.EndTime = time.Now()
+		if ctx.Err() != nil {
+			// An interrupted step says nothing about the rate.
+			results.Requests = append(results.Requests, step.Requests...)
+			return false
+		}
+		s := evaluateStep(lt.Search.SLO, rps, expectedRPS(lt, rps), step.CalculateStatistics())
+		fmt.Fprintf(e.opts.Log, "  %d RPS: %s\n", rps, describeStep(s))
+		search.Steps = append(search.Steps, s)
+		if s.Passed {
+			search.MaxPassingRPS = max(search.MaxPassingRPS, rps)
+		}
+		results.RPS = max(results.RPS, rps)
+		results.Requests = append(results.Requests, step.Requests...)
+		return s.Passed
+	}
+
+	start, maxRPS := lt.SearchStartRPS(), lt.Search.MaxRPS
+	if search.Strategy == config.SearchBinary {
+		// lo is the highest passing rate, hi the lowest failing one.
+		lo, hi := 0, maxRPS+1
+		if try(start) {
+			lo = start
+			if start < maxRPS && ctx.Err() == nil {
+				if try(maxRPS) {
+					lo = maxRPS
+				} else {
+					hi = maxRPS
+				}
+			}
+		} else {
+			// The target cannot take the start rate; look for the highest
+			// rate below it that it can take.
+			hi = start
+		}
+		for hi <= maxRPS && hi-lo > lt.SearchPrecision() && ctx.Err() == nil {
+			mid := lo + (hi-lo)/2
+			if try(mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+	} else {
+		for rps := start; rps <= maxRPS && ctx.Err() == nil; rps += lt.SearchStep() {
+			if !try(rps) {
+				break
+			}
+		}
+	}
+
+	results.EndTime = time.Now()
+	results.Duration = int(results.EndTime.Sub(results.StartTime).Round(time.Second) / time.Second)
+	return results
+}
+
+func searchStrategy(lt config.LoadTestConfig) string {
+	if lt.Search.Strategy == "" {
+		return config.SearchRamp
+	}
+	return lt.Search.Strategy
+}
+
+// evaluateStep checks the statistics of a step against the SLO. expected is
+// the total rate of the step, including endpoints with a rate of their own.
+func evaluateStep(slo config.SLOConfig, rps int, expected float64, stats report.Statistics) report.SearchStep {
+	step := report.SearchStep{
+		TargetRPS:   rps,
+		AchievedRPS: stats.RequestsPerSec,
+		Requests:    stats.TotalRequests,
+		P99Duration: stats.P99Duration,
+	}
+	if stats.TotalRequests > 0 {
+		step.ErrorRate = float64(stats.FailedRequests) / float64(stats.TotalRequests) * 100
+	}
+
+	if limit := time.Duration(slo.P99) * time.Millisecond; limit > 0 && step.P99Duration > limit {
+		step.Violations = append(step.Violations, fmt.Sprintf("p99 %s above %s", step.P99Duration.Round(time.Millisecond), limit))
+	}
+	if step.ErrorRate > slo.MaxErrorRate() {
+		step.Violations = append(step.Violations, fmt.Sprintf("error rate %.2f%% above %g%%", step.ErrorRate, slo.MaxErrorRate()))
+	}
+	if step.AchievedRPS < expected*slo.MinThroughput() {
+		step.Violations = append(step.Violations, fmt.Sprintf("achieved %.1f RPS, below %g%% of the target", step.AchievedRPS, slo.MinThroughput()*100))
+	}
+	step.Passed = len(step.Violations) == 0
+	return step
+}
+
+func describeStep(s report.SearchStep) string {
+	summary := fmt.Sprintf("achieved %.1f RPS, p99 %s, errors %.2f%%", s.AchievedRPS, s.P99Duration.Round(time.Millisecond), s.ErrorRate)
+	if s.Passed {
+		return "pass (" + summary + ")"
+	}
+	return fmt.Sprintf("fail (%s): %v", summary, s.Violations)
+}
+
+func (e *Engine) printSearchPlan(seed int64) {
+	lt := e.cfg.LoadTest
+	mask := e.cfg.Secrets.Mask
+	fmt.Fprintf(e.opts.Log, "Starting capacity search...\n")
+	fmt.Fprintf(e.opts.Log, "Domain: %s\n", mask(lt.Domain))
+	fmt.Fprintf(e.opts.Log, "Endpoints: %d\n", len(lt.Endpoints))
+	switch searchStrategy(lt) {
+	case config.SearchBinary:
+		fmt.Fprintf(e.opts.Log, "Strategy: binary search from %d to %d RPS (precision %d)\n", lt.SearchStartRPS(), lt.Search.MaxRPS, lt.SearchPrecision())
+	default:
+		fmt.Fprintf(e.opts.Log, "Strategy: ramp from %d to %d RPS in steps of %d\n", lt.SearchStartRPS(), lt.Search.MaxRPS, lt.SearchStep())
+	}
+	fmt.Fprintf(e.opts.Log, "Step duration: %ds\n", lt.SearchStepDuration())
+	slo := lt.Search.SLO
+	if slo.P99 > 0 {
+		fmt.Fprintf(e.opts.Log, "SLO: p99 <= %dms, ", slo.P99)
+	} else {
+		fmt.Fprintf(e.opts.Log, "SLO: ")
+	}
+	fmt.Fprintf(e.opts.Log, "error rate <= %g%%, achieved >= %g%% of target\n", slo.MaxErrorRate(), slo.MinThroughput()*100)
+	fmt.Fprintf(e.opts.Log, "Concurrency: %d\n", lt.Concurrency)
+	if warmup := describeWarmup(lt); warmup != "" {
+		fmt.Fprintf(e.opts.Log, "%s\n", warmup)
+	}
+	fmt.Fprintf(e.opts.Log, "Seed: %d\n", seed)
+	fmt.Fprintf(e.opts.Log, "\n")
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// tcpExecutor sends the body of an endpoint over TCP and reads the response
+// up to the delimiter. Like the HTTP client it keeps connections open and
+// reuses them for later requests to the same address.
+type tcpExecutor struct {
+	timeout time.Duration
+
+	mu   sync.Mutex
+	idle map[string][]*tcpConn
+}
+
+// tcpConn is a connection with the reader that buffers its responses.
+type tcpConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (x *tcpExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	start := time.Now()
+	err := x.exchange(ctx, t.endpoint.TCP.Address, t.endpoint.Body, t.endpoint.TCP.ResponseDelimiter())
+	result := report.RequestResult{
+		Timestamp: start,
+		Duration:  time.Since(start),
+		URL:       t.reportURL,
+		Protocol:  report.ProtocolTCP,
+	}
+	if err != nil {
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseBody)
+	}
+	record(result)
+}
+
+// exchange sends payload and waits for a response ending in delimiter. A
+// reused connection that turns out to be closed by the server is replaced
+// by a new one once.
+func (x *tcpExecutor) exchange(ctx context.Context, address, payload, delimiter string) error {
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		conn, reused := x.get(address)
+		if conn == nil {
+			c, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+			if err != nil {
+				return err
+			}
+			conn = &tcpConn{Conn: c, r: bufio.NewReader(c)}
+		}
+
+		err := roundTrip(ctx, conn, payload, delimiter)
+		if err == nil {
+			x.put(address, conn)
+			return nil
+		}
+		conn.Close()
+		if !reused || attempt > 0 || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func roundTrip(ctx context.Context, conn *tcpConn, payload, delimiter string) error {
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		return err
+	}
+	last := delimiter[len(delimiter)-1]
+	var resp strings.Builder
+	for {
+		chunk, err := conn.r.ReadString(last)
+		resp.WriteString(chunk)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(resp.String(), delimiter) {
+			return nil
+		}
+	}
+}
+
+func (x *tcpExecutor) get(address string) (*tcpConn, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	conns := x.idle[address]
+	if len(conns) == 0 {
+		return nil, false
+	}
+	conn := conns[len(conns)-1]
+	x.idle[address] = conns[:len(conns)-1]
+	return conn, true
+}
+
+func (x *tcpExecutor) put(address string, conn *tcpConn) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.idle == nil {
+		x.idle = make(map[string][]*tcpConn)
+	}
+	x.idle[address] = append(x.idle[address], conn)
+}
+
+func (x *tcpExecutor) close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, conns := range x.idle {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	x.idle = nil
+	return nil
+}
+
+// udpExecutor sends the body of an endpoint as one datagram and, when the
+// endpoint expects one, waits for a response datagram.
+type udpExecutor struct {
+	timeout time.Duration
+}
+
+// errNoResponse is reported when no response datagram arrived in time.
+var errNoResponse = errors.New("no response datagram received")
+
+func (x *udpExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	start := time.Now()
+	err := x.send(ctx, t.endpoint.UDP.Address, t.endpoint.Body, t.endpoint.UDP.ExpectResponse)
+	result := report.RequestResult{
+		Timestamp: start,
+		Duration:  time.Since(start),
+		URL:       t.reportURL,
+		Protocol:  report.ProtocolUDP,
+	}
+	switch {
+	case errors.Is(err, errNoResponse):
+		result.ErrorClass, result.Error = report.ErrorClassTimeout, "timeout ("+report.PhaseBody+"): "+err.Error()
+	case err != nil:
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseBody)
+	}
+	record(result)
+}
+
+func (x *udpExecutor) send(ctx context.Context, address, payload string, expectResponse bool) error {
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		return err
+	}
+	if !expectResponse {
+		return nil
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	deadline, _ := ctx.Deadline()
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 65535)
+	if _, err := conn.Read(buf); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return errNoResponse
+		}
+		return err
+	}
+	return nil
+}
+
+func (x *udpExecutor) close() error {
+	return nil
+}
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// sseExecutor reads a streamed response per request. It records the time to
+// the first event, the gap between consecutive events and the stream
+// lifetime as separate phases.
+//
+// Responses of type text/event-stream are split into Server-Sent Events;
+// any other streamed response, such as newline-delimited JSON or a long
+// poll, counts every non-empty line as an event.
+type sseExecutor struct {
+	// client has no overall timeout; timeout only bounds the wait for the
+	// response headers.
+	client  *http.Client
+	timeout time.Duration
+}
+
+func (x *sseExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	newResult := func(phase string, start time.Time) report.RequestResult {
+		return report.RequestResult{
+			Timestamp: start,
+			Duration:  time.Since(start),
+			URL:       t.reportURL,
+			Protocol:  report.ProtocolSSE,
+			Phase:     phase,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var timedOut, closed, runEnded atomic.Bool
+	headerTimer := time.AfterFunc(x.timeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+	defer headerTimer.Stop()
+
+	start := time.Now()
+	resp, err := x.open(ctx, t)
+	headerTimer.Stop()
+	session := newResult(report.PhaseSession, start)
+	if err != nil {
+		if timedOut.Load() {
+			session.ErrorClass, session.Error = report.ErrorClassTimeout, "timeout ("+report.PhaseHeader+"): no response headers received"
+		} else {
+			session.ErrorClass, session.Error = report.ClassifyError(err, report.PhaseHeader)
+		}
+		record(session)
+		return
+	}
+	defer resp.Body.Close()
+	session.StatusCode = resp.StatusCode
+	if class, message := report.ClassifyStatus(resp.StatusCode); class != "" {
+		session.ErrorClass, session.Error = class, message
+		record(session)
+		return
+	}
+
+	// The client ends the stream after the configured duration, or when
+	// the run ends for streams read until the server ends them. A stream
+	// the run end cuts off before its first event has not failed.
+	if d := t.endpoint.SSE.Duration; d > 0 {
+		timer := time.AfterFunc(time.Duration(d)*time.Second, func() {
+			closed.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+	} else if !t.end.IsZero() {
+		timer := time.AfterFunc(time.Until(t.end), func() {
+			runEnded.Store(true)
+			closed.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	last := start
+	err = readEvents(resp.Body, mediaType == "text/event-stream", func() {
+		now := time.Now()
+		if session.Messages == 0 {
+			result := newResult(report.PhaseFirstEvent, start)
+			result.Duration = now.Sub(start)
+			record(result)
+		} else {
+			result := newResult(report.PhaseEventGap, last)
+			result.Duration = now.Sub(last)
+			record(result)
+		}
+		last = now
+		session.Messages++
+	})
+
+	session.Duration = time.Since(start)
+	switch {
+	case err != nil && !closed.Load():
+		session.ErrorClass, session.Error = report.ClassifyError(err, report.PhaseBody)
+	case session.Messages == 0 && !runEnded.Load():
+		session.ErrorClass, session.Error = report.ErrorClassOther, "stream ended without events"
+	}
+	record(session)
+}
+
+// open sends the request, asking for an event stream unless the endpoint
+// sets its own Accept header.
+func (x *sseExecutor) open(ctx context.Context, t target) (*http.Response, error) {
+	req, err := newRequest(ctx, t.endpoint, t.url)
+	if err != nil {
+		return nil, err
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return withJar(x.client, t.jar).Do(req)
+}
+
+func (x *sseExecutor) close() error {
+	x.client.CloseIdleConnections()
+	return nil
+}
+
+// readEvents calls onEvent for every event read from r until the stream
+// ends. With sse an event is a block of lines with at least one data field,
+// terminated by a blank line, so comments used as heartbeats do not count;
+// otherwise every non-empty line is an event.
+func readEvents(r io.Reader, sse bool, onEvent func()) error {
+	br := bufio.NewReader(r)
+	hasData := false
+	for {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			// An event not terminated by a blank line is discarded.
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case !sse:
+			if line != "" {
+				onEvent()
+			}
+		case line == "":
+			if hasData {
+				onEvent()
+				hasData = false
+			}
+		case line == "data" || strings.HasPrefix(line, "data:"):
+			hasData = true
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// stopper aborts a run once its results meet one of the error stop
+// conditions. The request count and iteration limits are enforced by
+// sendLoad, which controls what is sent.
+type stopper struct {
+	cfg    config.StopConfig
+	cancel context.CancelFunc
+
+	mu                sync.Mutex
+	condition, reason string
+	consecutive       int
+	// buckets count the results of every second within the error rate
+	// window, oldest first.
+	buckets       []stopBucket
+	total, failed int
+	latency       *report.Histogram
+	lastCheck     time.Time
+}
+
+type stopBucket struct {
+	second        int64
+	total, failed int
+}
+
+// newStopper returns a stopper that calls cancel when a condition is met,
+// or nil when no error stop condition is configured.
+func newStopper(cfg config.StopConfig, cancel context.CancelFunc) *stopper {
+	if cfg.ConsecutiveErrors == 0 && cfg.ErrorRate == 0 && cfg.Thresholds == (config.ThresholdConfig{}) {
+		return nil
+	}
+	return &stopper{cfg: cfg, cancel: cancel, latency: report.NewHistogram()}
+}
+
+// wrap returns record extended with the stop condition checks.
+func (s *stopper) wrap(record func(report.RequestResult)) func(report.RequestResult) {
+	if s == nil {
+		return record
+	}
+	return func(result report.RequestResult) {
+		record(result)
+		s.observe(result)
+	}
+}
+
+// observe checks the stop conditions against a new result.
+func (s *stopper) observe(result report.RequestResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.condition != "" {
+		return
+	}
+	failed := result.Error != ""
+	s.total++
+	s.latency.Record(result.Duration)
+	if failed {
+		s.failed++
+		s.consecutive++
+	} else {
+		s.consecutive = 0
+	}
+
+	if n := s.cfg.ConsecutiveErrors; n > 0 && s.consecutive >= n {
+		s.stop(report.StopConsecutiveErrors, fmt.Sprintf("%d consecutive errors", s.consecutive))
+		return
+	}
+
+	if s.cfg.ErrorRate > 0 {
+		now := time.Now().Unix()
+		if len(s.buckets) == 0 || s.buckets[len(s.buckets)-1].second != now {
+			s.buckets = append(s.buckets, stopBucket{second: now})
+		}
+		b := &s.buckets[len(s.buckets)-1]
+		b.total++
+		if failed {
+			b.failed++
+		}
+		window := int64(s.cfg.WindowSeconds())
+		for len(s.buckets) > 0 && s.buckets[0].second <= now-window {
+			s.buckets = s.buckets[1:]
+		}
+		var total, failed int
+		for _, b := range s.buckets {
+			total += b.total
+			failed += b.failed
+		}
+		if rate := percent(failed, total); total >= s.cfg.MinimumRequests() && rate > s.cfg.ErrorRate {
+			s.stop(report.StopErrorRate, fmt.Sprintf("error rate %.2f%% over the last %ds above %g%%", rate, window, s.cfg.ErrorRate))
+			return
+		}
+	}
+
+	// Percentiles are costly to read, so the thresholds are checked at
+	// most once a second.
+	if s.cfg.Thresholds != (config.ThresholdConfig{}) && s.total >= s.cfg.MinimumRequests() && time.Since(s.lastCheck) >= time.Second {
+		s.lastCheck = time.Now()
+		if reason := s.breach(); reason != "" {
+			s.stop(report.StopThreshold, reason)
+		}
+	}
+}
+
+// breach describes the first threshold the results so far breach, or
+// returns "" when they meet all of them.
+func (s *stopper) breach() string {
+	t := s.cfg.Thresholds
+	for _, p := range []struct {
+		name  string
+		limit int
+		value float64
+	}{{"p95", t.P95, 0.95}, {"p99", t.P99, 0.99}} {
+		limit := time.Duration(p.limit) * time.Millisecond
+		if d := s.latency.Percentile(p.value); limit > 0 && d > limit {
+			return fmt.Sprintf("threshold breached: %s %s above %s", p.name, d.Round(time.Millisecond), limit)
+		}
+	}
+	if rate := percent(s.failed, s.total); t.ErrorRate > 0 && rate > t.ErrorRate {
+		return fmt.Sprintf("threshold breached: error rate %.2f%% above %g%%", rate, t.ErrorRate)
+	}
+	return ""
+}
+
+// stop records the first condition met and aborts the run.
+func (s *stopper) stop(condition, reason string) {
+	s.condition, s.reason = condition, reason
+	s.cancel()
+}
+
+// stopped returns the condition met and its description, or empty strings
+// when none was. It is nil-safe.
+func (s *stopper) stopped() (string, string) {
+	if s == nil {
+		return "", ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.condition, s.reason
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,364 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// Request templates are Go text/template strings in endpoint paths, header
+// values and bodies, and in the checks of the response: expected cookie
+// names and body text. They are parsed once, when the engine is created,
+// into one template set; every worker executes its own clone of the set so
+// that the functions can keep per-request state. Checks are rendered with
+// the request, so they see the same random values and feeder rows.
+
+const randStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// templateFuncs returns the functions available in request templates.
+// The random functions draw from rng, which is seeded for every request;
+// feeder reads the values of a row through row, which picks the row of a
+// feeder for the current request; cookie reads the worker's cookie jar;
+// env reads the environment variables the templates name.
+func templateFuncs(rng *rand.Rand, row func(feeder string) (*feeder, []string, error), cookie func(name string) string, env func(name string) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"uuid": func() string { return newUUID(rng) },
+		"now":  time.Now,
+		"randInt": func(lo, hi int) int {
+			if hi <= lo {
+				return lo
+			}
+			return lo + rng.IntN(hi-lo+1)
+		},
+		"randString": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = randStringChars[rng.IntN(len(randStringChars))]
+			}
+			return string(b)
+		},
+		"base64": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"sha256": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"hmac": func(key, message string) string {
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(message))
+			return hex.EncodeToString(mac.Sum(nil))
+		},
+		"env": env,
+		"feeder": func(name, column string) (string, error) {
+			f, values, err := row(name)
+			if err != nil {
+				return "", err
+			}
+			i, ok := f.columns[column]
+			if !ok {
+				return "", fmt.Errorf("feeder %q has no column %q", name, column)
+			}
+			return values[i], nil
+		},
+		"cookie": cookie,
+	}
+}
+
+// newUUID returns a random (version 4) UUID drawn from rng.
+func newUUID(rng *rand.Rand) string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], rng.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], rng.Uint64())
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// feeder serves the rows of a CSV file.
+type feeder struct {
+	columns map[string]int
+	rows    [][]string
+	random  bool
+	next    atomic.Uint64
+}
+
+// loadFeeder reads the CSV file of f.
+func loadFeeder(f config.Feeder) (*feeder, error) {
+	file, err := os.Open(f.File)
+	if err != nil {
+		return nil, fmt.Errorf("feeder %q: %w", f.Name, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("feeder %q: %w", f.Name, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("feeder %q: %s has no rows after the header", f.Name, f.File)
+	}
+	fd := &feeder{
+		columns: make(map[string]int),
+		rows:    records[1:],
+		random:  f.Order == config.FeederRandom,
+	}
+	for i, name := range records[0] {
+		fd.columns[strings.TrimSpace(name)] = i
+	}
+	return fd, nil
+}
+
+// pick returns the row for the next request; random rows are drawn from
+// rng.
+func (f *feeder) pick(rng *rand.Rand) []string {
+	if f.random {
+		return f.rows[rng.IntN(len(f.rows))]
+	}
+	return f.rows[(f.next.Add(1)-1)%uint64(len(f.rows))]
+}
+
+// templateName returns the name of a field template of endpoint i.
+func templateName(i int, field string) string {
+	return fmt.Sprintf("endpoints[%d].%s", i, field)
+}
+
+// feederCall matches a feeder call with a literal feeder name.
+var feederCall = regexp.MustCompile(`\bfeeder\s+"([^"]*)"`)
+
+// compileTemplates parses the templates of every endpoint and loads the
+// feeders. The template set is nil when no endpoint uses templates. uses
+// lists the feeders the templates of every endpoint read by name, so that
+// their rows can be picked when the request is scheduled.
+func compileTemplates(lt config.LoadTestConfig) (set *template.Template, feeders map[string]*feeder, uses [][]string, err error) {
+	feeders = make(map[string]*feeder)
+	for _, f := range lt.Feeders {
+		fd, err := loadFeeder(f)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		feeders[f.Name] = fd
+	}
+
+	set = template.New("").Funcs(templateFuncs(nil, nil, nil, nil))
+	uses = make([][]string, len(lt.Endpoints))
+	templated := false
+	parse := func(i int, field, text string) error {
+		if !strings.Contains(text, "{{") {
+			return nil
+		}
+		if _, err := set.New(templateName(i, field)).Parse(text); err != nil {
+			return fmt.Errorf("invalid template in loadtest.endpoints[%d].%s: %w", i, field, err)
+		}
+		for _, m := range feederCall.FindAllStringSubmatch(text, -1) {
+			if _, ok := feeders[m[1]]; ok && !slices.Contains(uses[i], m[1]) {
+				uses[i] = append(uses[i], m[1])
+			}
+		}
+		templated = true
+		return nil
+	}
+	for i, ep := range lt.Endpoints {
+		if err := parse(i, "path", ep.Path); err != nil {
+			return nil, nil, nil, err
+		}
+		for _, name := range slices.Sorted(maps.Keys(ep.Headers)) {
+			if err := parse(i, "headers."+name, ep.Headers[name]); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if err := parse(i, "body", ep.Body); err != nil {
+			return nil, nil, nil, err
+		}
+		for j, name := range ep.ExpectCookies {
+			if err := parse(i, fmt.Sprintf("expect_cookies[%d]", j), name); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if err := parse(i, "expect_body", ep.ExpectBody); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if !templated {
+		return nil, feeders, uses, nil
+	}
+	return set, feeders, uses, nil
+}
+
+// renderer executes request templates for one worker.
+type renderer struct {
+	domain    string
+	endpoints []endpointTemplates
+	feeders   map[string]*feeder
+	// rows are the feeder rows picked for the current request, so that
+	// every column read in one request comes from the same row.
+	rows map[string][]string
+	// jar is the cookie jar of the worker for the current request.
+	jar http.CookieJar
+	// rng is seeded with the seed of every request, so that the random
+	// functions return the same values whichever worker renders it.
+	pcg *rand.PCG
+	rng *rand.Rand
+	// envNames are the environment variables the templates name
+	// literally; their values are masked as secrets.
+	envNames map[string]bool
+	buf      bytes.Buffer
+}
+
+// endpointTemplates are the templates of one endpoint; fields without a
+// template are nil.
+type endpointTemplates struct {
+	path    *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+	// expectCookies is nil when no expected cookie name is a template.
+	expectCookies []*template.Template
+	expectBody    *template.Template
+}
+
+// newRenderer returns a renderer for one worker, or nil when no endpoint
+// uses templates.
+func (e *Engine) newRenderer() *renderer {
+	if e.templates == nil {
+		return nil
+	}
+	r := &renderer{
+		domain:    e.cfg.LoadTest.Domain,
+		endpoints: make([]endpointTemplates, len(e.cfg.LoadTest.Endpoints)),
+		feeders:   e.feeders,
+		rows:      make(map[string][]string),
+		pcg:       rand.NewPCG(0, 0),
+		envNames:  make(map[string]bool),
+	}
+	r.rng = rand.New(r.pcg)
+	for _, ep := range e.cfg.LoadTest.Endpoints {
+		for _, name := range ep.TemplateEnv() {
+			r.envNames[name] = true
+		}
+	}
+	// Parsing is not repeated: the clone shares the parsed templates and
+	// only gets its own function bindings.
+	set := template.Must(e.templates.Clone()).Funcs(templateFuncs(r.rng, r.row, r.cookie, r.env))
+	for i, ep := range e.cfg.LoadTest.Endpoints {
+		r.endpoints[i].path = set.Lookup(templateName(i, "path"))
+		r.endpoints[i].body = set.Lookup(templateName(i, "body"))
+		r.endpoints[i].expectBody = set.Lookup(templateName(i, "expect_body"))
+		for j := range ep.ExpectCookies {
+			if tmpl := set.Lookup(templateName(i, fmt.Sprintf("expect_cookies[%d]", j))); tmpl != nil {
+				if r.endpoints[i].expectCookies == nil {
+					r.endpoints[i].expectCookies = make([]*template.Template, len(ep.ExpectCookies))
+				}
+				r.endpoints[i].expectCookies[j] = tmpl
+			}
+		}
+		for name := range ep.Headers {
+			if tmpl := set.Lookup(templateName(i, "headers."+name)); tmpl != nil {
+				if r.endpoints[i].headers == nil {
+					r.endpoints[i].headers = make(map[string]*template.Template)
+				}
+				r.endpoints[i].headers[name] = tmpl
+			}
+		}
+	}
+	return r
+}
+
+// env reads an environment variable named literally in a template. A name
+// computed at run time is refused, as its value would not be masked.
+func (r *renderer) env(name string) (string, error) {
+	if !r.envNames[name] {
+		return "", fmt.Errorf("env %q: templates can only read variables they name literally", name)
+	}
+	return os.Getenv(name), nil
+}
+
+func (r *renderer) row(name string) (*feeder, []string, error) {
+	f, ok := r.feeders[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown feeder %q", name)
+	}
+	values, ok := r.rows[name]
+	if !ok {
+		values = f.pick(r.rng)
+		r.rows[name] = values
+	}
+	return f, values, nil
+}
+
+// cookie returns the value of a cookie the worker holds for the domain.
+func (r *renderer) cookie(name string) string {
+	return cookieValue(r.jar, r.domain, name)
+}
+
+// render returns t with the templates of its endpoint executed. Targets
+// that are not configured endpoints, such as replayed requests, are
+// returned unchanged.
+func (r *renderer) render(t target) (target, error) {
+	if t.index < 0 {
+		return t, nil
+	}
+	clear(r.rows)
+	maps.Copy(r.rows, t.rows)
+	r.pcg.Seed(t.seed, t.seed)
+	tmpls := r.endpoints[t.index]
+	ep := t.endpoint
+	var err error
+	if ep.Path, err = r.execute(tmpls.path, ep.Path); err != nil {
+		return t, err
+	}
+	if tmpls.headers != nil {
+		ep.Headers = maps.Clone(ep.Headers)
+		for name, tmpl := range tmpls.headers {
+			if ep.Headers[name], err = r.execute(tmpl, ep.Headers[name]); err != nil {
+				return t, err
+			}
+		}
+	}
+	if ep.Body, err = r.execute(tmpls.body, ep.Body); err != nil {
+		return t, err
+	}
+	if tmpls.expectCookies != nil {
+		ep.ExpectCookies = slices.Clone(ep.ExpectCookies)
+		for j, tmpl := range tmpls.expectCookies {
+			if ep.ExpectCookies[j], err = r.execute(tmpl, ep.ExpectCookies[j]); err != nil {
+				return t, err
+			}
+		}
+	}
+	if ep.ExpectBody, err = r.execute(tmpls.expectBody, ep.ExpectBody); err != nil {
+		return t, err
+	}
+	t.endpoint = ep
+	t.url = ep.URL(r.domain)
+	return t, nil
+}
+
+// execute runs tmpl, or returns text unchanged when the field has no
+// template.
+func (r *renderer) execute(tmpl *template.Template, text string) (string, error) {
+	if tmpl == nil {
+		return text, nil
+	}
+	r.buf.Reset()
+	if err := tmpl.Execute(&r.buf, nil); err != nil {
+		return "", fmt.Errorf("template %s: %w", tmpl.Name(), err)
+	}
+	return r.buf.String(), nil
+}
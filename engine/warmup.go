@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// runWarmup sends the warm-up traffic that precedes measurement and returns
+// its requests, tagged as warm-up. No stop condition applies to it. Its
+// random choices are drawn from a generator of its own and the feeders
+// start over after it, so that a run with a seed sends the same measured
+// requests with or without a warm-up.
+func (e *Engine) runWarmup(ctx context.Context, executors map[string]executor, auth *authenticator, seed int64) *report.Warmup {
+	lt := e.cfg.LoadTest
+	warmup := &report.Warmup{
+		RPS:       lt.WarmupRPS(),
+		StartTime: time.Now(),
+		Included:  lt.Warmup.Include,
+	}
+	fmt.Fprintf(e.opts.Log, "Warming up for %ds...\n", lt.Warmup.Duration)
+
+	results := &report.Results{}
+	record := e.recorder(results)
+	rng := rand.New(rand.NewPCG(uint64(seed), ^uint64(seed)))
+	e.sendLoad(ctx, executors, auth, rng, warmup.RPS, lt.Warmup.Duration, config.StopConfig{}, func(result report.RequestResult) {
+		result.Warmup = true
+		record(result)
+	})
+	warmup.EndTime = time.Now()
+	warmup.Requests = results.Requests
+	e.resetFeeders()
+
+	stats := warmup.Statistics()
+	fmt.Fprintf(e.opts.Log, "Warm-up done: %d requests, avg %s, p99 %s, errors %d\n\n",
+		stats.TotalRequests, stats.AvgDuration.Round(time.Microsecond), stats.P99Duration.Round(time.Microsecond), stats.FailedRequests)
+	return warmup
+}
+
+// describeWarmup returns the warm-up line of the test plan, or "" when the
+// run has no warm-up.
+func describeWarmup(lt config.LoadTestConfig) string {
+	if lt.Warmup.Duration == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("Warm-up: %ds at %d RPS", lt.Warmup.Duration, lt.WarmupRPS())
+	if lt.Warmup.Include {
+		return s + ", included in the statistics"
+	}
+	return s + ", excluded from the statistics"
+}
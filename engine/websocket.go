@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// defaultWebSocketInterval is the time between messages when the endpoint
+// does not set one.
+const defaultWebSocketInterval = time.Second
+
+// websocketExecutor opens a connection for every request and plays the
+// scripted session of the endpoint on it. It records the handshake, the
+// round trip of every message and the connection as a whole as separate
+// phases.
+type websocketExecutor struct {
+	timeout time.Duration
+}
+
+// pendingMessage is a sent message whose echo has not arrived yet.
+type pendingMessage struct {
+	payload string
+	sent    time.Time
+}
+
+func (x *websocketExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	wsURL := websocketURL(t.url)
+	reportURL := websocketURL(t.reportURL)
+	newResult := func(phase string, start time.Time) report.RequestResult {
+		return report.RequestResult{
+			Timestamp: start,
+			Duration:  time.Since(start),
+			URL:       reportURL,
+			Protocol:  report.ProtocolWebSocket,
+			Phase:     phase,
+		}
+	}
+
+	start := time.Now()
+	conn, err := x.dial(ctx, t, wsURL)
+	connect := newResult(report.PhaseConnect, start)
+	if err != nil {
+		connect.ErrorClass, connect.Error = classifyWebSocketError(err, report.PhaseDial)
+		record(connect)
+		return
+	}
+	connect.StatusCode = http.StatusSwitchingProtocols
+	record(connect)
+
+	// The reader matches incoming messages to the oldest pending message
+	// with the same payload, so servers may answer out of order.
+	var mu sync.Mutex
+	var pending []pendingMessage
+	received := 0
+	echoed := make(chan struct{}, 1)
+	readErr := make(chan error, 1)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			var msg string
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				readErr <- err
+				return
+			}
+			now := time.Now()
+			mu.Lock()
+			received++
+			for i, p := range pending {
+				if p.payload == msg {
+					pending = append(pending[:i], pending[i+1:]...)
+					result := newResult(report.PhaseMessage, p.sent)
+					result.Duration = now.Sub(p.sent)
+					record(result)
+					break
+				}
+			}
+			mu.Unlock()
+			select {
+			case echoed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	dropErr := x.play(ctx, conn, t.endpoint.WebSocket, &mu, &pending, echoed, readErr)
+	conn.Close()
+	<-readerDone
+
+	session := newResult(report.PhaseSession, start)
+	session.Messages = received
+	if dropErr != nil {
+		session.ErrorClass, session.Error = classifyWebSocketError(dropErr, report.PhaseBody)
+	}
+	record(session)
+
+	// Messages still pending were never answered.
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range pending {
+		result := newResult(report.PhaseMessage, p.sent)
+		if dropErr != nil {
+			result.ErrorClass, result.Error = session.ErrorClass, session.Error
+		} else {
+			result.ErrorClass, result.Error = report.ErrorClassTimeout, "timeout ("+report.PhaseBody+"): no echo received"
+		}
+		record(result)
+	}
+}
+
+// play sends the scripted messages until the session is over, then waits
+// for the outstanding echoes. It returns the error that dropped the
+// connection, or nil when the client ended the session.
+func (x *websocketExecutor) play(ctx context.Context, conn *websocket.Conn, ws config.WebSocketConfig, mu *sync.Mutex, pending *[]pendingMessage, echoed <-chan struct{}, readErr <-chan error) error {
+	interval := time.Duration(ws.Interval) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultWebSocketInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Without a duration every message is sent once.
+	var deadline <-chan time.Time
+	if ws.Duration > 0 {
+		timer := time.NewTimer(time.Duration(ws.Duration) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for sent := 0; ws.Duration > 0 || sent < len(ws.Messages); sent++ {
+		if len(ws.Messages) > 0 {
+			payload := ws.Messages[sent%len(ws.Messages)]
+			mu.Lock()
+			*pending = append(*pending, pendingMessage{payload: payload, sent: time.Now()})
+			mu.Unlock()
+			if err := websocket.Message.Send(conn, payload); err != nil {
+				return err
+			}
+			if ws.Duration == 0 && sent == len(ws.Messages)-1 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case <-deadline:
+			return x.awaitEchoes(ctx, mu, pending, echoed, readErr)
+		case <-ticker.C:
+		}
+	}
+	return x.awaitEchoes(ctx, mu, pending, echoed, readErr)
+}
+
+// awaitEchoes waits until every sent message was echoed, giving up after
+// the request timeout.
+func (x *websocketExecutor) awaitEchoes(ctx context.Context, mu *sync.Mutex, pending *[]pendingMessage, echoed <-chan struct{}, readErr <-chan error) error {
+	timeout := time.NewTimer(x.timeout)
+	defer timeout.Stop()
+	for {
+		mu.Lock()
+		done := len(*pending) == 0
+		mu.Unlock()
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timeout.C:
+			return nil
+		case err := <-readErr:
+			return err
+		case <-echoed:
+		}
+	}
+}
+
+// dial performs the opening handshake, sending the endpoint headers and
+// the cookies of the worker. The origin is the http URL the endpoint was
+// configured with.
+func (x *websocketExecutor) dial(ctx context.Context, t target, wsURL string) (*websocket.Conn, error) {
+	cfg, err := websocket.NewConfig(wsURL, t.url)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range t.endpoint.Headers {
+		cfg.Header.Set(name, value)
+	}
+	if t.jar != nil {
+		if u, err := url.Parse(t.url); err == nil {
+			for _, c := range t.jar.Cookies(u) {
+				cfg.Header.Add("Cookie", c.String())
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+	return cfg.DialContext(ctx)
+}
+
+func (x *websocketExecutor) close() error {
+	return nil
+}
+
+// websocketURL maps an http or https URL to the ws or wss URL of the same
+// resource.
+func websocketURL(url string) string {
+	if rest, ok := strings.CutPrefix(url, "https://"); ok {
+		return "wss://" + rest
+	}
+	if rest, ok := strings.CutPrefix(url, "http://"); ok {
+		return "ws://" + rest
+	}
+	return url
+}
+
+// classifyWebSocketError classifies handshake and connection errors. A
+// connection closed by the server counts as reset.
+func classifyWebSocketError(err error, phase string) (report.ErrorClass, string) {
+	var dialErr *websocket.DialError
+	if errors.As(err, &dialErr) {
+		err = dialErr.Err
+	}
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return report.ErrorClassConnectionReset, "connection closed by server"
+	case errors.Is(err, websocket.ErrBadStatus):
+		return report.ErrorClassOther, "handshake rejected: " + err.Error()
+	}
+	return report.ClassifyError(err, phase)
+}
@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// target is a single request to send: an endpoint and its full URL.
+type target struct {
+	url string
+	// reportURL is the URL results are recorded under. It is url before
+	// request templates were rendered, so that requests to one endpoint
+	// are counted together.
+	reportURL string
+	// index is the position of the endpoint in the config, or -1 for
+	// requests that are not configured endpoints.
+	index    int
+	endpoint config.Endpoint
+	// jar is the cookie jar of the worker sending the request, nil when
+	// cookies are disabled.
+	jar http.CookieJar
+	// seed seeds the random template functions of the request, and rows
+	// are the feeder rows picked for it when it was scheduled.
+	seed uint64
+	rows map[string][]string
+	// end is when the run that scheduled the request stops sending, zero
+	// when it has no end. Streams read until the server ends them are
+	// closed then, so that they cannot keep the run going.
+	end time.Time
+}
+
+// executor sends requests of one protocol. The worker loop picks the
+// executor by endpoint type.
+type executor interface {
+	// execute sends a single request for t and passes its outcome,
+	// including when it started and how long it took, to record.
+	// Connection-oriented executors record one result per connection
+	// phase.
+	execute(ctx context.Context, t target, record func(report.RequestResult))
+	// close releases connections held by the executor.
+	close() error
+}
+
+// newExecutors returns an executor for every endpoint type used by
+// endpoints. Executors that need to resolve something before the first
+// request, such as gRPC message descriptors, do so here.
+func (e *Engine) newExecutors(ctx context.Context, endpoints []config.Endpoint) (map[string]executor, error) {
+	executors := map[string]executor{
+		config.EndpointHTTP:    &httpExecutor{client: e.client},
+		config.EndpointGraphQL: &graphqlExecutor{client: e.client},
+	}
+
+	var grpcEndpoints []config.Endpoint
+	for _, ep := range endpoints {
+		if ep.Protocol() == config.EndpointGRPC {
+			grpcEndpoints = append(grpcEndpoints, ep)
+		}
+	}
+	if len(grpcEndpoints) > 0 {
+		exec, err := newGRPCExecutor(ctx, e.cfg.LoadTest.Domain, grpcEndpoints, e.client.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		executors[config.EndpointGRPC] = exec
+	}
+	executors[config.EndpointWebSocket] = &websocketExecutor{timeout: e.client.Timeout}
+	executors[config.EndpointDNS] = &dnsExecutor{timeout: e.client.Timeout}
+	executors[config.EndpointTCP] = &tcpExecutor{timeout: e.client.Timeout}
+	executors[config.EndpointUDP] = &udpExecutor{timeout: e.client.Timeout}
+	// Streams outlive the request timeout, which only bounds the wait for
+	// the response headers.
+	executors[config.EndpointSSE] = &sseExecutor{client: &http.Client{Transport: e.opts.Transport}, timeout: e.client.Timeout}
+	return executors, nil
+}
+
+func closeExecutors(executors map[string]executor) {
+	for _, exec := range executors {
+		exec.close()
+	}
+}
+
+// runWorkers starts concurrency workers that send every target received on
+// work and pass the outcome to record. The returned WaitGroup is done once
+// work is closed and drained. Once ctx is canceled the remaining targets
+// are dropped, and requests aborted by the cancellation are not recorded.
+// With a limit of iterations every worker finishes after sending that many
+// targets, without waiting for work to be closed; 0 means no limit.
+func (e *Engine) runWorkers(ctx context.Context, executors map[string]executor, auth *authenticator, concurrency, iterations int, work <-chan target, record func(report.RequestResult)) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jar := e.newJar()
+			r := e.newRenderer()
+			for i := 0; iterations == 0 || i < iterations; i++ {
+				t, ok := <-work
+				if !ok {
+					return
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if jar != nil && e.cfg.LoadTest.Cookies.Reset == config.CookieResetIteration {
+					jar = e.newJar()
+				}
+				t.jar = jar
+				if r != nil {
+					r.jar = jar
+				}
+
+				if r != nil {
+					rendered, err := r.render(t)
+					if err != nil {
+						record(report.RequestResult{
+							Timestamp:  time.Now(),
+							URL:        t.reportURL,
+							Error:      err.Error(),
+							ErrorClass: report.ErrorClassOther,
+						})
+						continue
+					}
+					t = rendered
+				}
+				if auth != nil {
+					if err := auth.apply(ctx, &t); err != nil {
+						if ctx.Err() == nil {
+							record(report.RequestResult{
+								Timestamp:  time.Now(),
+								URL:        t.reportURL,
+								Error:      err.Error(),
+								ErrorClass: report.ErrorClassAuth,
+							})
+						}
+						continue
+					}
+				}
+
+				executors[t.endpoint.Protocol()].execute(ctx, t, func(result report.RequestResult) {
+					if result.Error != "" && ctx.Err() != nil {
+						return
+					}
+					record(result)
+				})
+			}
+		}()
+	}
+	return &wg
+}
+
+// httpExecutor sends HTTP requests with a shared client.
+type httpExecutor struct {
+	client *http.Client
+}
+
+func (x *httpExecutor) execute(ctx context.Context, t target, record func(report.RequestResult)) {
+	start := time.Now()
+	resp, err := x.doRequest(ctx, t)
+	elapsed := time.Since(start)
+
+	result := report.RequestResult{
+		Timestamp:  start,
+		Duration:   elapsed,
+		StatusCode: 0,
+		Error:      "",
+		URL:        t.reportURL,
+	}
+
+	if err != nil {
+		result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseHeader)
+	} else {
+		result.StatusCode = resp.StatusCode
+		// The body is only kept when it is checked.
+		var body []byte
+		if t.endpoint.ExpectBody != "" {
+			body, err = io.ReadAll(resp.Body)
+		} else {
+			_, err = io.Copy(io.Discard, resp.Body)
+		}
+		resp.Body.Close()
+		if err != nil {
+			result.ErrorClass, result.Error = report.ClassifyError(err, report.PhaseBody)
+		} else {
+			result.ErrorClass, result.Error = report.ClassifyStatus(resp.StatusCode)
+		}
+		if result.ErrorClass == "" {
+			result.ErrorClass, result.Error = checkResponse(t, body)
+		}
+	}
+	record(result)
+}
+
+// checkResponse runs the checks of the endpoint of t on a response with
+// body: the expected cookies and the expected body text.
+func checkResponse(t target, body []byte) (report.ErrorClass, string) {
+	if class, msg := checkCookies(t); class != "" {
+		return class, msg
+	}
+	if want := t.endpoint.ExpectBody; want != "" && !bytes.Contains(body, []byte(want)) {
+		return report.ErrorClassCheck, fmt.Sprintf("response body does not contain %q", want)
+	}
+	return "", ""
+}
+
+// doRequest sends a single request for t, with the cookies of its worker.
+func (x *httpExecutor) doRequest(ctx context.Context, t target) (*http.Response, error) {
+	req, err := newRequest(ctx, t.endpoint, t.url)
+	if err != nil {
+		return nil, err
+	}
+	return withJar(x.client, t.jar).Do(req)
+}
+
+// newRequest builds the HTTP request for endpoint, with its method,
+// headers and body.
+func newRequest(ctx context.Context, endpoint config.Endpoint, url string) (*http.Request, error) {
+	var body io.Reader
+	if endpoint.Body != "" {
+		body = strings.NewReader(endpoint.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, endpoint.RequestMethod(), url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+func (x *httpExecutor) close() error {
+	x.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package agent implements distributed load generation: agents run a share
+// of a load test on request of a controller and send back an aggregate of
+// their results instead of every request.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+// DefaultPort is used when an agent address has no port.
+const DefaultPort = "7070"
+
+// TokenEnv is the environment variable the shared agent token is read from
+// when no flag sets it. Run requests carry credentials and secrets and make
+// the agent read files, so agents only accept requests with the token.
+const TokenEnv = "METEOR_SHOWER_AGENT_TOKEN"
+
+// RunRequest asks an agent to run its share of a load test.
+type RunRequest struct {
+	LoadTest config.LoadTestConfig
+	// Secrets are masked in the agent's log output.
+	Secrets config.Secrets
+	// StartAt is when every agent starts sending requests, so that the
+	// load ramps up at the same time on all of them.
+	StartAt time.Time
+}
+
+// RunResponse is the outcome of a RunRequest.
+type RunResponse struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Aggregate *report.Aggregate
+}
+
+// Status reports whether an agent is running a test.
+type Status struct {
+	Running bool
+}
+
+// RunFunc executes a load test and returns its results. ctx is canceled
+// when the controller goes away.
+type RunFunc func(ctx context.Context, req RunRequest) (*report.Results, error)
+
+// Handler serves the agent API to controllers that present token as a
+// bearer token; all other requests are rejected, as are all requests when
+// token is empty. Only one test runs at a time; further run requests are
+// rejected until it has finished.
+func Handler(token string, run RunFunc) http.Handler {
+	var mu sync.Mutex
+	running := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/status", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := Status{Running: running}
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("/v1/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RunRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid run request: %v", err), http.StatusBadRequest)
+			return
+		}
+		cfg := &config.Config{LoadTest: req.LoadTest, Secrets: req.Secrets}
+		if issues := cfg.Validate(); len(issues) > 0 {
+			http.Error(w, fmt.Sprintf("invalid configuration: %s", issues[0]), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		if running {
+			mu.Unlock()
+			http.Error(w, "agent is already running a test", http.StatusConflict)
+			return
+		}
+		running = true
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running = false
+			mu.Unlock()
+		}()
+
+		select {
+		case <-time.After(time.Until(req.StartAt)):
+		case <-r.Context().Done():
+			return
+		}
+
+		results, err := run(r.Context(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, RunResponse{
+			StartTime: results.StartTime,
+			EndTime:   results.EndTime,
+			Aggregate: results.Summarize(),
+		})
+	})
+	return authenticate(token, mux)
+}
+
+// authenticate passes on the requests that carry token as a bearer token.
+func authenticate(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid agent token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Client talks to a single agent.
+type Client struct {
+	// Addr is the base URL of the agent, e.g. http://host1:7070.
+	Addr  string
+	token string
+	http  *http.Client
+}
+
+// NewClient returns a client for the agent at addr, given as host,
+// host:port or a URL, that authenticates with token. The port defaults to
+// DefaultPort. tlsConfig verifies agents served over TLS; with it, an
+// address without a scheme uses https, otherwise http.
+func NewClient(addr, token string, tlsConfig *tls.Config) *Client {
+	addr = strings.TrimSuffix(addr, "/")
+	if !strings.Contains(addr, "://") {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, DefaultPort)
+		}
+		scheme := "http://"
+		if tlsConfig != nil {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &Client{Addr: addr, token: token, http: &http.Client{Transport: transport}}
+}
+
+// Status returns whether the agent is busy. It fails if the agent cannot
+// be reached.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Addr+"/v1/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	var status Status
+	if err := c.do(req, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Run sends a run request and waits until the agent has finished the test.
+func (c *Client) Run(ctx context.Context, run RunRequest) (*RunResponse, error) {
+	body, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Addr+"/v1/run", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp RunResponse
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Aggregate == nil {
+		return nil, fmt.Errorf("agent %s returned no results", c.Addr)
+	}
+	return &resp, nil
+}
+
+func (c *Client) do(req *http.Request, v any) error {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("agent %s: %w", c.Addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("agent %s: %s: %s", c.Addr, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("agent %s: invalid response: %w", c.Addr, err)
+	}
+	return nil
+}
+
+// Split divides total into n shares that differ by at most one, larger
+// shares first.
+func Split(total, n int) []int {
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = total / n
+		if i < total%n {
+			shares[i]++
+		}
+	}
+	return shares
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/engine"
+	"github.com/kitsystemyou/meteor-shower/internal/agent"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+func (c *CLI) agentCommand(args []string) error {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	listen := fs.String("listen", "127.0.0.1:"+agent.DefaultPort, "address the agent listens on")
+	token := fs.String("token", "", "shared token controllers must present (default $"+agent.TokenEnv+")")
+	tlsCert := fs.String("tls-cert", "", "certificate file to serve the agent API over TLS")
+	tlsKey := fs.String("tls-key", "", "private key file of the TLS certificate")
+
+	fs.Usage = func() {
+		usage := `Run a load generation agent. A controller started with
+"meteor-shower run --agents" sends the agent its share of the load test,
+and the agent reports back aggregated results.
+
+Agents start sending requests at a time chosen by the controller, so the
+clocks of all machines should be synchronized (e.g. with NTP).
+
+Run requests carry credentials and secrets, and make the agent read
+feeder files and environment variables, so the agent only accepts
+controllers that present its token. Serve the agent over TLS when it
+listens on a network other controllers' traffic crosses.
+
+Usage:
+  meteor-shower agent [flags]
+
+Flags:
+  --listen string     address the agent listens on (default "127.0.0.1:7070")
+  --token string      shared token controllers must present; required
+                      (default $METEOR_SHOWER_AGENT_TOKEN)
+  --tls-cert string   certificate file to serve the agent API over TLS
+  --tls-key string    private key file of the TLS certificate
+
+Examples:
+  # Start two agents on one machine
+  export METEOR_SHOWER_AGENT_TOKEN=$(openssl rand -hex 32)
+  meteor-shower agent --listen 127.0.0.1:7071 &
+  meteor-shower agent --listen 127.0.0.1:7072 &
+  meteor-shower run --agents localhost:7071,localhost:7072
+
+  # Serve an agent on all interfaces over TLS
+  meteor-shower agent --listen :7070 --tls-cert agent.crt --tls-key agent.key
+  meteor-shower run --agents host1 --agent-ca ca.crt
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *token == "" {
+		*token = os.Getenv(agent.TokenEnv)
+	}
+	if *token == "" {
+		return fmt.Errorf("an agent token is required: set --token or %s", agent.TokenEnv)
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+
+	server := &http.Server{
+		Handler: agent.Handler(*token, func(ctx context.Context, req agent.RunRequest) (*report.Results, error) {
+			eng, err := engine.New(&config.Config{LoadTest: req.LoadTest, Secrets: req.Secrets}, engine.Options{Log: c.stderr})
+			if err != nil {
+				return nil, err
+			}
+			return eng.Run(ctx)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+	}
+	if *tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			ln.Close()
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+		fmt.Fprintf(c.stderr, "Agent listening on %s (TLS)\n\n", ln.Addr())
+	} else {
+		fmt.Fprintf(c.stderr, "Agent listening on %s\n", ln.Addr())
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() {
+			fmt.Fprintf(c.stderr, "Warning: without TLS the token, credentials and secrets of run requests cross the network in plaintext\n")
+		}
+		fmt.Fprintf(c.stderr, "\n")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("agent failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to stop agent: %w", err)
+	}
+	return nil
+}
+
+// agentStartDelay gives every agent time to receive its run request before
+// the common start time.
+const agentStartDelay = 2 * time.Second
+
+// agentFlags are the run flags that say how to reach the agents.
+type agentFlags struct {
+	token string
+	// ca is a PEM file of the certificates agents served over TLS are
+	// verified with, in addition to the system roots.
+	ca string
+}
+
+// clientTLS returns the TLS configuration agents are verified with, or nil
+// when no CA file is given.
+func (f agentFlags) clientTLS() (*tls.Config, error) {
+	if f.ca == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(f.ca)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent CA: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in agent CA %s", f.ca)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// executeDistributed splits the load test across agents, starts them at
+// the same time and merges their aggregated results.
+func (c *CLI) executeDistributed(cfg *config.Config, addrs []string, flags agentFlags) (*report.Results, error) {
+	lt := cfg.LoadTest
+	if lt.RPS < len(addrs) {
+		return nil, fmt.Errorf("rps (%d) must be at least the number of agents (%d)", lt.RPS, len(addrs))
+	}
+	if flags.token == "" {
+		flags.token = os.Getenv(agent.TokenEnv)
+	}
+	if flags.token == "" {
+		return nil, fmt.Errorf("an agent token is required: set --agent-token or %s", agent.TokenEnv)
+	}
+	tlsConfig, err := flags.clientTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	clients := make([]*agent.Client, len(addrs))
+	for i, addr := range addrs {
+		clients[i] = agent.NewClient(addr, flags.token, tlsConfig)
+		status, err := clients[i].Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if status.Running {
+			return nil, fmt.Errorf("agent %s is already running a test", clients[i].Addr)
+		}
+	}
+
+	rpsShares := agent.Split(lt.RPS, len(clients))
+	concurrencyShares := agent.Split(lt.Concurrency, len(clients))
+
+	var targets []string
+	for _, ep := range lt.Endpoints {
+		targets = append(targets, ep.URL(lt.Domain))
+	}
+
+	fmt.Fprintf(c.stderr, "Starting distributed load test...\n")
+	fmt.Fprintf(c.stderr, "Domain: %s\n", cfg.Secrets.Mask(lt.Domain))
+	fmt.Fprintf(c.stderr, "Endpoints: %d\n", len(targets))
+	fmt.Fprintf(c.stderr, "RPS: %d\n", lt.RPS)
+	fmt.Fprintf(c.stderr, "Concurrency: %d\n", lt.Concurrency)
+	fmt.Fprintf(c.stderr, "Duration: %ds\n", lt.Duration)
+	fmt.Fprintf(c.stderr, "Agents: %d\n", len(clients))
+
+	startAt := time.Now().Add(agentStartDelay)
+	responses := make([]*agent.RunResponse, len(clients))
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		share := lt
+		share.RPS = rpsShares[i]
+		// Every agent needs at least one worker.
+		share.Concurrency = max(concurrencyShares[i], 1)
+		// Agents with the same seed would send the same sequence.
+		if lt.Seed != nil {
+			seed := *lt.Seed + int64(i)
+			share.Seed = &seed
+		}
+		share.Endpoints = make([]config.Endpoint, len(lt.Endpoints))
+		for j, ep := range lt.Endpoints {
+			ep.RPS /= float64(len(clients))
+			if ep.Concurrency > 0 {
+				ep.Concurrency = max(agent.Split(ep.Concurrency, len(clients))[i], 1)
+			}
+			share.Endpoints[j] = ep
+		}
+		fmt.Fprintf(c.stderr, "  [%d] %s (rps: %d, concurrency: %d)\n", i+1, client.Addr, share.RPS, share.Concurrency)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = client.Run(ctx, agent.RunRequest{
+				LoadTest: share,
+				Secrets:  cfg.Secrets,
+				StartAt:  startAt,
+			})
+		}()
+	}
+	fmt.Fprintf(c.stderr, "\n")
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	results := &report.Results{
+		URLs:        targets,
+		RPS:         lt.RPS,
+		Concurrency: lt.Concurrency,
+		Duration:    lt.Duration,
+		Aggregate:   report.NewAggregate(),
+	}
+	for _, resp := range responses {
+		if results.StartTime.IsZero() || resp.StartTime.Before(results.StartTime) {
+			results.StartTime = resp.StartTime
+		}
+		if resp.EndTime.After(results.EndTime) {
+			results.EndTime = resp.EndTime
+		}
+		results.Aggregate.Merge(resp.Aggregate)
+	}
+	return results, nil
+}
+
+// splitAgents parses a comma separated list of agent addresses.
+func splitAgents(value string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
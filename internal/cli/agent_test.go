@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/engine"
+	"github.com/kitsystemyou/meteor-shower/internal/agent"
+	"github.com/kitsystemyou/meteor-shower/report"
+)
+
+const testAgentToken = "test-token"
+
+// startAgents starts n agents on loopback listeners and returns their
+// addresses and the run requests they receive.
+func startAgents(t *testing.T, n int) ([]string, func() []agent.RunRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []agent.RunRequest
+	addrs := make([]string, n)
+	for i := range addrs {
+		srv := httptest.NewServer(agent.Handler(testAgentToken, func(ctx context.Context, req agent.RunRequest) (*report.Results, error) {
+			mu.Lock()
+			received = append(received, req)
+			mu.Unlock()
+			eng, err := engine.New(&config.Config{LoadTest: req.LoadTest, Secrets: req.Secrets}, engine.Options{})
+			if err != nil {
+				return nil, err
+			}
+			return eng.Run(ctx)
+		}))
+		t.Cleanup(srv.Close)
+		addrs[i] = srv.URL
+	}
+	return addrs, func() []agent.RunRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(received)
+	}
+}
+
+func TestExecuteDistributed(t *testing.T) {
+	var weighted, fixed atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/weighted":
+			weighted.Add(1)
+		case "/fixed":
+			fixed.Add(1)
+		}
+	}))
+	defer target.Close()
+
+	addrs, received := startAgents(t, 2)
+	cfg := config.Default()
+	cfg.LoadTest.Domain = target.URL
+	cfg.LoadTest.Endpoints = []config.Endpoint{
+		{Path: "/weighted", Weight: 1},
+		{Path: "/fixed", RPS: 4},
+	}
+	cfg.LoadTest.RPS = 11
+	cfg.LoadTest.Concurrency = 3
+	cfg.LoadTest.Duration = 1
+
+	c := &CLI{stdout: io.Discard, stderr: io.Discard}
+	results, err := c.executeDistributed(cfg, addrs, agentFlags{token: testAgentToken})
+	if err != nil {
+		t.Fatalf("executeDistributed: %v", err)
+	}
+
+	reqs := received()
+	if len(reqs) != 2 {
+		t.Fatalf("agents received %d run requests, want 2", len(reqs))
+	}
+	var rps, concurrency []int
+	for _, req := range reqs {
+		rps = append(rps, req.LoadTest.RPS)
+		concurrency = append(concurrency, req.LoadTest.Concurrency)
+		if got := req.LoadTest.Endpoints[1].RPS; got != 2 {
+			t.Errorf("fixed endpoint rps of an agent = %g, want 2", got)
+		}
+	}
+	slices.Sort(rps)
+	slices.Sort(concurrency)
+	if !slices.Equal(rps, []int{5, 6}) {
+		t.Errorf("agent rps shares = %v, want [5 6]", rps)
+	}
+	if !slices.Equal(concurrency, []int{1, 2}) {
+		t.Errorf("agent concurrency shares = %v, want [1 2]", concurrency)
+	}
+
+	// The agents send the weighted rate for one second and 2 RPS each to
+	// the fixed endpoint.
+	stats := results.CalculateStatistics()
+	if weighted.Load() != 11 || fixed.Load() != 4 {
+		t.Errorf("target received %d weighted and %d fixed requests, want 11 and 4", weighted.Load(), fixed.Load())
+	}
+	if stats.TotalRequests != 15 || stats.SuccessRequests != 15 {
+		t.Errorf("merged aggregate has %d requests, %d successful, want 15 and 15", stats.TotalRequests, stats.SuccessRequests)
+	}
+	if got := stats.URLCounts[target.URL+"/weighted"]; got != 11 {
+		t.Errorf("merged aggregate has %d weighted requests, want 11", got)
+	}
+	if got := stats.URLCounts[target.URL+"/fixed"]; got != 4 {
+		t.Errorf("merged aggregate has %d fixed requests, want 4", got)
+	}
+}
+
+func TestExecuteDistributedRejectsWrongToken(t *testing.T) {
+	addrs, received := startAgents(t, 2)
+	cfg := config.Default()
+
+	c := &CLI{stdout: io.Discard, stderr: io.Discard}
+	_, err := c.executeDistributed(cfg, addrs, agentFlags{token: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("executeDistributed with a wrong token: got %v, want a 401 error", err)
+	}
+	if n := len(received()); n != 0 {
+		t.Errorf("agents ran %d tests for a controller with a wrong token", n)
+	}
+}
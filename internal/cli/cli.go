@@ -33,6 +33,10 @@ func (c *CLI) Run() error {
 		return c.runCommand(c.args[1:])
 	case "config":
 		return c.configCommand(c.args[1:])
+	case "record":
+		return c.recordCommand(c.args[1:])
+	case "agent":
+		return c.agentCommand(c.args[1:])
 	case "version":
 		return c.versionCommand(c.args[1:])
 	case "help":
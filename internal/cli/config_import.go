@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/internal/importer"
+)
+
+func (c *CLI) configImportCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.stderr, "Error: import source required\n\n")
+		c.printConfigImportUsage()
+		return fmt.Errorf("import source required")
+	}
+
+	source := args[0]
+
+	switch source {
+	case "openapi":
+		return c.configImportOpenAPICommand(args[1:])
+	case "har":
+		return c.configImportHARCommand(args[1:])
+	case "curl":
+		return c.configImportCurlCommand(args[1:])
+	case "-h", "--help", "help":
+		c.printConfigImportUsage()
+		return nil
+	default:
+		fmt.Fprintf(c.stderr, "Error: unknown import source: %s\n\n", source)
+		c.printConfigImportUsage()
+		return fmt.Errorf("unknown import source: %s", source)
+	}
+}
+
+func (c *CLI) printConfigImportUsage() {
+	usage := `Generate a configuration file from an existing description of the traffic.
+
+Usage:
+  meteor-shower config import [source] [flags] <file>
+
+Available Sources:
+  openapi     Generate endpoints from an OpenAPI 3 specification
+  har         Generate endpoints from an HTTP Archive (HAR) recording
+  curl        Generate endpoints from curl commands
+
+Use "meteor-shower config import [source] --help" for more information about a source.
+`
+	fmt.Fprint(c.stderr, usage)
+}
+
+func (c *CLI) configImportOpenAPICommand(args []string) error {
+	fs := flag.NewFlagSet("config import openapi", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	out := addImportOutputFlags(fs)
+	domain := fs.String("domain", "", "target domain (default is the first server URL of the spec)")
+	var tags, operations, weights stringList
+	fs.Var(&tags, "tag", "import only operations with this tag (repeatable, comma separated)")
+	fs.Var(&operations, "operation", "import only the operation with this operationId (repeatable, comma separated)")
+	fs.Var(&weights, "weight", "weight for an operation as operationId=N or \"METHOD /path\"=N (repeatable)")
+	defaultWeight := fs.Float64("default-weight", 1.0, "weight for operations without --weight")
+
+	fs.Usage = func() {
+		usage := `Generate a configuration file with one endpoint per operation of an
+OpenAPI 3 specification (YAML or JSON).
+
+Path, query and header parameters are filled with their examples, or with
+placeholders derived from their schema. Request bodies use the example of
+the JSON media type, or one generated from its schema.
+
+Usage:
+  meteor-shower config import openapi [flags] <spec>
+
+Flags:
+  -o, --output string       output file path (default is stdout)
+  -f, --force               overwrite existing file
+  --domain string           target domain (default is the first server URL of the spec)
+  --tag string              import only operations with this tag (repeatable, comma separated)
+  --operation string        import only the operation with this operationId (repeatable, comma separated)
+  --weight string           weight for an operation as operationId=N or "METHOD /path"=N (repeatable)
+  --default-weight float    weight for operations without --weight (default 1)
+
+Examples:
+  # Import every operation
+  meteor-shower config import openapi spec.yaml -o config.yaml
+
+  # Import only the "users" tag and hit listUsers three times as often
+  meteor-shower config import openapi spec.yaml --tag users --weight listUsers=3
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one OpenAPI specification file is required")
+	}
+
+	weightMap, err := weights.weights()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read OpenAPI specification: %w", err)
+	}
+
+	cfg, err := importer.OpenAPI(data, importer.OpenAPIOptions{
+		Domain:        *domain,
+		Tags:          tags.values(),
+		OperationIDs:  operations.values(),
+		Weights:       weightMap,
+		DefaultWeight: *defaultWeight,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.writeImportedConfig(out, cfg, "Generated from OpenAPI specification "+positional[0])
+}
+
+func (c *CLI) configImportHARCommand(args []string) error {
+	fs := flag.NewFlagSet("config import har", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	out := addImportOutputFlags(fs)
+	domain := fs.String("domain", "", "import only requests to this origin or host (default is the most frequent origin)")
+	includeStatic := fs.Bool("include-static", false, "keep requests for scripts, stylesheets, images, fonts and media")
+	noCookies := fs.Bool("no-cookies", false, "drop the cookies sent with each request")
+
+	fs.Usage = func() {
+		usage := `Generate a configuration file from an HTTP Archive (HAR) recorded with
+browser dev tools. Each distinct request (method, path and body) becomes an
+endpoint weighted by how often it appears in the archive. Headers, cookies
+and request bodies are kept.
+
+Usage:
+  meteor-shower config import har [flags] <file>
+
+Flags:
+  -o, --output string    output file path (default is stdout)
+  -f, --force            overwrite existing file
+  --domain string        import only requests to this origin or host
+                         (default is the most frequent origin)
+  --include-static       keep requests for scripts, stylesheets, images, fonts and media
+  --no-cookies           drop the cookies sent with each request
+
+Examples:
+  # Import the API calls of a recorded session
+  meteor-shower config import har session.har -o config.yaml
+
+  # Import only requests to api.example.com
+  meteor-shower config import har session.har --domain api.example.com
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one HAR file is required")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read HAR file: %w", err)
+	}
+
+	cfg, err := importer.HAR(data, importer.HAROptions{
+		Domain:        *domain,
+		IncludeStatic: *includeStatic,
+		SkipCookies:   *noCookies,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.writeImportedConfig(out, cfg, "Generated from HAR file "+positional[0])
+}
+
+func (c *CLI) configImportCurlCommand(args []string) error {
+	fs := flag.NewFlagSet("config import curl", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	out := addImportOutputFlags(fs)
+	domain := fs.String("domain", "", "import only requests to this origin or host (default is the most frequent origin)")
+	includeStatic := fs.Bool("include-static", false, "keep requests for scripts, stylesheets, images, fonts and media")
+	noCookies := fs.Bool("no-cookies", false, "drop cookies given with -b/--cookie or a Cookie header")
+
+	fs.Usage = func() {
+		usage := `Generate a configuration file from curl commands, e.g. from "Copy as cURL"
+in browser dev tools. The file may contain several commands, one per line or
+continued with trailing backslashes. Identical requests are merged and
+weighted by how often they appear.
+
+Usage:
+  meteor-shower config import curl [flags] [file]
+
+Reads from stdin when no file (or "-") is given.
+
+Flags:
+  -o, --output string    output file path (default is stdout)
+  -f, --force            overwrite existing file
+  --domain string        import only requests to this origin or host
+                         (default is the most frequent origin)
+  --include-static       keep requests for scripts, stylesheets, images, fonts and media
+  --no-cookies           drop cookies given with -b/--cookie or a Cookie header
+
+Examples:
+  # Import commands saved in a file
+  meteor-shower config import curl requests.sh -o config.yaml
+
+  # Import a command from the clipboard
+  pbpaste | meteor-shower config import curl
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return fmt.Errorf("at most one file is accepted")
+	}
+
+	origin := "Generated from curl commands"
+	var data []byte
+	if len(positional) == 0 || positional[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(positional[0])
+		origin += " in " + positional[0]
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read curl commands: %w", err)
+	}
+
+	cfg, err := importer.Curl(string(data), importer.CurlOptions{
+		Domain:        *domain,
+		IncludeStatic: *includeStatic,
+		SkipCookies:   *noCookies,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.writeImportedConfig(out, cfg, origin)
+}
+
+// importOutputFlags are the flags shared by every import source.
+type importOutputFlags struct {
+	output      *string
+	outputShort *string
+	force       *bool
+	forceShort  *bool
+}
+
+func addImportOutputFlags(fs *flag.FlagSet) *importOutputFlags {
+	return &importOutputFlags{
+		output:      fs.String("output", "", "output file path (default is stdout)"),
+		outputShort: fs.String("o", "", "output file path (default is stdout)"),
+		force:       fs.Bool("force", false, "overwrite existing file"),
+		forceShort:  fs.Bool("f", false, "overwrite existing file"),
+	}
+}
+
+func (c *CLI) writeImportedConfig(out *importOutputFlags, cfg *config.Config, origin string) error {
+	outFile := *out.output
+	if *out.outputShort != "" {
+		outFile = *out.outputShort
+	}
+
+	header := []string{
+		"yaml-language-server: $schema=" + config.SchemaURL,
+		origin,
+	}
+
+	var w io.Writer = c.stdout
+	if outFile != "" {
+		if _, err := os.Stat(outFile); err == nil && !*out.force && !*out.forceShort {
+			return fmt.Errorf("file %s already exists. Use -f to overwrite", outFile)
+		}
+		f, err := os.Create(outFile)
+		if err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := config.Encode(w, cfg, header...); err != nil {
+		return err
+	}
+
+	if outFile != "" {
+		fmt.Fprintf(c.stdout, "Configuration file created: %s (%d endpoints)\n", outFile, len(cfg.LoadTest.Endpoints))
+	}
+	return nil
+}
+
+// parseInterspersed parses fs allowing flags to appear after positional
+// arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// stringList is a repeatable flag whose values may also be comma separated.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func (s stringList) values() []string {
+	var values []string
+	for _, v := range s {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// weights parses key=N entries.
+func (s stringList) weights() (map[string]float64, error) {
+	weights := make(map[string]float64, len(s))
+	for _, v := range s {
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid weight %q: expected key=N", v)
+		}
+		w, err := strconv.ParseFloat(v[i+1:], 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q: expected a non-negative number", v)
+		}
+		weights[v[:i]] = w
+	}
+	return weights, nil
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+
+	"github.com/kitsystemyou/meteor-shower/config"
 )
 
 const defaultConfigTemplate = `# meteor-shower configuration file for load testing
@@ -47,6 +49,8 @@ func (c *CLI) configInitCommand(args []string) error {
 	outputShort := fs.String("o", "config.yaml", "output file path")
 	force := fs.Bool("force", false, "overwrite existing file")
 	forceShort := fs.Bool("f", false, "overwrite existing file")
+	schema := fs.String("schema", config.SchemaURL, "JSON Schema location referenced by the yaml-language-server header")
+	noSchema := fs.Bool("no-schema", false, "omit the yaml-language-server header")
 
 	fs.Usage = func() {
 		usage := `Generate a default configuration file.
@@ -57,6 +61,10 @@ Usage:
 Flags:
   -o, --output string    output file path (default "config.yaml")
   -f, --force            overwrite existing file
+  --schema string        JSON Schema location referenced by the
+                         yaml-language-server header (default is the
+                         published schema)
+  --no-schema            omit the yaml-language-server header
 
 Examples:
   # Generate config.yaml in current directory
@@ -67,6 +75,9 @@ Examples:
 
   # Overwrite existing file
   meteor-shower config init -f
+
+  # Reference a local schema generated with "config schema"
+  meteor-shower config init --schema ./config.schema.json
 `
 		fmt.Fprint(c.stderr, usage)
 	}
@@ -89,8 +100,13 @@ Examples:
 		return fmt.Errorf("file %s already exists. Use -f to overwrite", outFile)
 	}
 
+	content := defaultConfigTemplate
+	if !*noSchema && *schema != "" {
+		content = fmt.Sprintf("# yaml-language-server: $schema=%s\n", *schema) + content
+	}
+
 	// Write config file
-	if err := os.WriteFile(outFile, []byte(defaultConfigTemplate), 0644); err != nil {
+	if err := os.WriteFile(outFile, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -110,6 +126,14 @@ func (c *CLI) configCommand(args []string) error {
 	switch subcommand {
 	case "init":
 		return c.configInitCommand(args[1:])
+	case "validate":
+		return c.configValidateCommand(args[1:])
+	case "show":
+		return c.configShowCommand(args[1:])
+	case "schema":
+		return c.configSchemaCommand(args[1:])
+	case "import":
+		return c.configImportCommand(args[1:])
 	default:
 		fmt.Fprintf(c.stderr, "Error: unknown subcommand: %s\n\n", subcommand)
 		c.printConfigUsage()
@@ -125,6 +149,10 @@ Usage:
 
 Available Subcommands:
   init        Generate a default configuration file
+  validate    Validate a configuration file
+  show        Print the effective configuration
+  schema      Print the JSON Schema of the configuration file
+  import      Generate a configuration file from other formats
 
 Use "meteor-shower config [subcommand] --help" for more information about a subcommand.
 `
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+func (c *CLI) configSchemaCommand(args []string) error {
+	fs := flag.NewFlagSet("config schema", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	outputFile := fs.String("output", "", "output file path (default is stdout)")
+	outputShort := fs.String("o", "", "output file path (default is stdout)")
+
+	fs.Usage = func() {
+		usage := `Print the JSON Schema of the configuration file.
+
+The schema can be used by editors to autocomplete and validate config files,
+e.g. with the YAML language server:
+
+  # yaml-language-server: $schema=./config.schema.json
+
+Usage:
+  meteor-shower config schema [flags]
+
+Flags:
+  -o, --output string    output file path (default is stdout)
+
+Examples:
+  # Print the schema
+  meteor-shower config schema
+
+  # Write the schema next to the config file
+  meteor-shower config schema -o config.schema.json
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	outFile := *outputFile
+	if *outputShort != "" {
+		outFile = *outputShort
+	}
+
+	var w io.Writer = c.stdout
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		if err != nil {
+			return fmt.Errorf("failed to create schema file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config.GenerateSchema()); err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	if outFile != "" {
+		fmt.Fprintf(c.stdout, "Schema file created: %s\n", outFile)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+func (c *CLI) configShowCommand(args []string) error {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	overrides := addOverrideFlags(fs)
+
+	fs.Usage = func() {
+		usage := `Print the effective configuration after applying defaults, the config
+file, METEOR_SHOWER_* environment variables and command-line overrides.
+Secret values are masked.
+
+Usage:
+  meteor-shower config show [flags]
+
+Flags:
+  --config string        config file (default is ./config.yaml)
+` + overrideFlagsUsage + `
+Examples:
+  # Show the effective configuration
+  meteor-shower config show
+
+  # Show the configuration a run with overrides would use
+  meteor-shower config show --config prod.yaml --rps 100
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := overrides.load()
+	if err != nil {
+		return err
+	}
+
+	source := cfg.Path
+	if source == "" {
+		source = "defaults"
+	}
+	if len(cfg.EnvOverrides) > 0 {
+		source += ", environment: " + strings.Join(cfg.EnvOverrides, ", ")
+	}
+	return config.Encode(c.stdout, cfg, fmt.Sprintf("Effective configuration (source: %s)", source))
+}
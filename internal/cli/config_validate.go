@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+func (c *CLI) configValidateCommand(args []string) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	configFile := fs.String("config", "", "config file (default is ./config.yaml)")
+
+	fs.Usage = func() {
+		usage := `Validate a configuration file and report every problem found.
+
+Usage:
+  meteor-shower config validate [flags] [file]
+
+Flags:
+  --config string   config file (default is ./config.yaml)
+
+Checks:
+  - YAML syntax and value types
+  - unknown keys
+  - unresolvable ${ENV_VAR} and ${file:...} references
+  - malformed domain URLs, negative weights and other invalid values
+
+Examples:
+  # Validate config.yaml in current directory
+  meteor-shower config validate
+
+  # Validate a specific file
+  meteor-shower config validate my-config.yaml
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *configFile
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		path = "config.yaml"
+	}
+
+	issues, err := config.ValidateFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintf(c.stdout, "%s: configuration is valid\n", path)
+		return nil
+	}
+
+	for _, issue := range issues {
+		location := path
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s:%d", path, issue.Line)
+		}
+		issue.Line = 0
+		fmt.Fprintf(c.stdout, "%s: %s\n", location, issue)
+	}
+	return fmt.Errorf("%s has %d problem(s)", path, len(issues))
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kitsystemyou/meteor-shower/internal/importer"
+)
+
+// maxRecordedBody is the largest request body kept in the recording.
+// Larger bodies are still forwarded but recorded without a body.
+const maxRecordedBody = 1 << 20
+
+func (c *CLI) recordCommand(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	out := addImportOutputFlags(fs)
+	listen := fs.String("listen", ":9000", "address the recording proxy listens on")
+	target := fs.String("target", "", "URL of the service to forward requests to")
+	includeStatic := fs.Bool("include-static", false, "keep requests for scripts, stylesheets, images, fonts and media")
+	noCookies := fs.Bool("no-cookies", false, "drop the cookies sent with each request")
+
+	fs.Usage = func() {
+		usage := `Run a reverse proxy that forwards traffic to a service and records the
+requests it sees. When stopped with Ctrl+C, the recording is written as a
+configuration file.
+
+Requests to the same route are merged into one endpoint weighted by how
+often it was seen. Numeric IDs, UUIDs and other ID-like path segments are
+replaced by environment references such as ${USERS_ID:-42}, defaulting to
+the first value recorded.
+
+Usage:
+  meteor-shower record --target <url> [flags]
+
+Flags:
+  --listen string        address the recording proxy listens on (default ":9000")
+  --target string        URL of the service to forward requests to (required)
+  -o, --output string    output file path (default is stdout)
+  -f, --force            overwrite existing file
+  --include-static       keep requests for scripts, stylesheets, images, fonts and media
+  --no-cookies           drop the cookies sent with each request
+
+Examples:
+  # Record traffic sent to localhost:9000 and save it as config.yaml
+  meteor-shower record --listen :9000 --target http://localhost:8080 -o config.yaml
+`
+		fmt.Fprint(c.stderr, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	if *target == "" {
+		fs.Usage()
+		return fmt.Errorf("--target is required")
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || (targetURL.Scheme != "http" && targetURL.Scheme != "https") || targetURL.Host == "" {
+		return fmt.Errorf("invalid target %q: must be an absolute http or https URL", *target)
+	}
+	origin := targetURL.Scheme + "://" + targetURL.Host
+	base := origin + strings.TrimSuffix(targetURL.Path, "/")
+
+	// Fail before recording rather than losing the session at the end.
+	outFile := *out.output
+	if *out.outputShort != "" {
+		outFile = *out.outputShort
+	}
+	if outFile != "" && !*out.force && !*out.forceShort {
+		if _, err := os.Stat(outFile); err == nil {
+			return fmt.Errorf("file %s already exists. Use -f to overwrite", outFile)
+		}
+	}
+
+	recorder := importer.NewRecorder(importer.RecordOptions{
+		IncludeStatic: *includeStatic,
+		SkipCookies:   *noCookies,
+	})
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = targetURL.Host
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		req := resp.Request
+		recorded := recordedRequestFrom(req.Context())
+		recorder.Record(base, recorded.req, recorded.body, resp.Header.Get("Content-Type"))
+		fmt.Fprintf(c.stderr, "%s %s -> %d\n", recorded.req.Method, recorded.req.URL.RequestURI(), resp.StatusCode)
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		fmt.Fprintf(c.stderr, "%s %s -> error: %v\n", req.Method, req.URL.RequestURI(), err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(req.Body, maxRecordedBody+1))
+			if err != nil {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+			if len(body) > maxRecordedBody {
+				body = nil
+			}
+
+			// The outgoing request is rewritten by the director, so keep
+			// the incoming one for the recording.
+			recorded := &recordedRequest{req: req.Clone(req.Context()), body: body}
+			proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), recordedRequestKey{}, recorded)))
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+	}
+
+	fmt.Fprintf(c.stderr, "Recording requests on %s, forwarding to %s\n", ln.Addr(), base)
+	fmt.Fprintf(c.stderr, "Press Ctrl+C to stop and write the configuration\n\n")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("recording proxy failed: %w", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to stop recording proxy: %w", err)
+	}
+
+	fmt.Fprintf(c.stderr, "\nRecorded %d requests\n", recorder.Len())
+	cfg, err := recorder.Config(origin)
+	if err != nil {
+		return err
+	}
+	return c.writeImportedConfig(out, cfg, "Recorded from traffic to "+origin)
+}
+
+// recordedRequest is the request as received by the proxy.
+type recordedRequest struct {
+	req  *http.Request
+	body []byte
+}
+
+type recordedRequestKey struct{}
+
+func recordedRequestFrom(ctx context.Context) *recordedRequest {
+	return ctx.Value(recordedRequestKey{}).(*recordedRequest)
+}
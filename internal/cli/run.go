@@ -1,27 +1,27 @@
 package cli
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"io"
-	"math/rand"
-	"net/http"
-	"sync"
-	"time"
-
-	"github.com/kitsystemyou/meteor-shower/internal/config"
-	"github.com/kitsystemyou/meteor-shower/internal/report"
+	"strconv"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+	"github.com/kitsystemyou/meteor-shower/engine"
+	"github.com/kitsystemyou/meteor-shower/report"
 )
 
 func (c *CLI) runCommand(args []string) error {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	fs.SetOutput(c.stderr)
 
-	configFile := fs.String("config", "", "config file (default is ./config.yaml)")
-	rps := fs.Int("rps", 0, "requests per second (overrides config)")
-	concurrency := fs.Int("concurrency", 0, "number of concurrent clients (overrides config)")
-	output := fs.String("output", "", "output format: html, json (overrides config)")
-	outputShort := fs.String("o", "", "output format: html, json (overrides config)")
+	overrides := addOverrideFlags(fs)
+	agents := fs.String("agents", "", "comma separated agent addresses to distribute the load across")
+	agentToken := fs.String("agent-token", "", "shared token of the agents (default $METEOR_SHOWER_AGENT_TOKEN)")
+	agentCA := fs.String("agent-ca", "", "PEM file of the CA certificates agents served over TLS are verified with")
+	planOnly := fs.Bool("plan-only", false, "print the schedule of the requests without sending them")
+	dryRun := fs.Bool("dry-run", false, "print the targets, request mix, rate schedule and connection estimate without sending load")
+	validate := fs.Bool("validate", false, "with --dry-run, send one request to every endpoint")
 
 	fs.Usage = func() {
 		usage := `Run executes load test against the target endpoint.
@@ -30,9 +30,21 @@ Usage:
   meteor-shower run [flags]
 
 Flags:
-  --rps int              requests per second (overrides config)
-  --concurrency int      number of concurrent clients (overrides config)
-  -o, --output string    output format: html, json (overrides config)
+` + overrideFlagsUsage + `  --plan-only            print the offset and endpoint of every request in the
+                         order they would be sent, without sending anything
+  --dry-run              print the resolved targets with masked credentials, the
+                         request mix, the rate schedule, the expected number of
+                         requests and a connection estimate, without sending load
+  --validate             with --dry-run, send one request to every endpoint and
+                         fail when any of them fails
+  --agents string        comma separated agent addresses (host[:port], default
+                         port 7070) to split the rps and concurrency across;
+                         use https:// addresses for agents served over TLS
+  --agent-token string   shared token of the agents
+                         (default $METEOR_SHOWER_AGENT_TOKEN)
+  --agent-ca string      PEM file of the CA certificates agents served over
+                         TLS are verified with; addresses without a scheme
+                         then use https
 
 Global Flags:
   --config string   config file (default is ./config.yaml)
@@ -44,64 +56,62 @@ Global Flags:
 		return err
 	}
 
-	cfg, err := config.LoadConfig(*configFile)
+	cfg, err := overrides.load()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	// Override config with command-line flags
-	if *rps > 0 {
-		cfg.LoadTest.RPS = *rps
-	}
-	if *concurrency > 0 {
-		cfg.LoadTest.Concurrency = *concurrency
-	}
-	if *output != "" {
-		cfg.LoadTest.Output = *output
-	} else if *outputShort != "" {
-		cfg.LoadTest.Output = *outputShort
+		return err
 	}
 
 	// Validate configuration
-	if cfg.LoadTest.RPS <= 0 {
-		return fmt.Errorf("rps must be greater than 0")
-	}
-	if cfg.LoadTest.Concurrency <= 0 {
-		return fmt.Errorf("concurrency must be greater than 0")
-	}
-	if cfg.LoadTest.Duration <= 0 {
-		return fmt.Errorf("duration must be greater than 0")
-	}
-	if len(cfg.LoadTest.Endpoints) == 0 {
-		return fmt.Errorf("at least one endpoint must be specified")
+	if issues := cfg.Validate(); len(issues) > 0 {
+		return fmt.Errorf("invalid configuration: %s", config.JoinIssues(issues))
 	}
 
-	// Normalize endpoint weights (set default to 1.0 if not specified)
-	for i := range cfg.LoadTest.Endpoints {
-		if cfg.LoadTest.Endpoints[i].Weight <= 0 {
-			cfg.LoadTest.Endpoints[i].Weight = 1.0
+	if *validate && !*dryRun {
+		return fmt.Errorf("--validate requires --dry-run")
+	}
+	if *dryRun {
+		eng, err := engine.New(cfg, engine.Options{})
+		if err != nil {
+			return err
 		}
+		return eng.DryRun(context.Background(), c.stdout, *validate)
 	}
 
-	// Build target URLs
-	var targets []string
-	for _, ep := range cfg.LoadTest.Endpoints {
-		targets = append(targets, cfg.LoadTest.Domain+ep.Path)
+	if *planOnly {
+		eng, err := engine.New(cfg, engine.Options{})
+		if err != nil {
+			return err
+		}
+		return eng.WritePlan(c.stdout)
 	}
 
-	fmt.Fprintf(c.stderr, "Starting load test...\n")
-	fmt.Fprintf(c.stderr, "Domain: %s\n", cfg.LoadTest.Domain)
-	fmt.Fprintf(c.stderr, "Endpoints: %d\n", len(targets))
-	for i, ep := range cfg.LoadTest.Endpoints {
-		fmt.Fprintf(c.stderr, "  [%d] %s (weight: %.2f)\n", i+1, ep.Path, ep.Weight)
+	var results *report.Results
+	if addrs := splitAgents(*agents); len(addrs) > 0 {
+		if cfg.LoadTest.RunMode() != config.ModeLoad {
+			return fmt.Errorf("--agents is only supported in load mode")
+		}
+		if cfg.LoadTest.Checkpoint.Interval > 0 {
+			return fmt.Errorf("--agents does not support checkpoints")
+		}
+		if cfg.LoadTest.Stop != (config.StopConfig{}) {
+			return fmt.Errorf("--agents does not support stop conditions")
+		}
+		if cfg.LoadTest.Warmup.Duration > 0 {
+			return fmt.Errorf("--agents does not support a warm-up")
+		}
+		if results, err = c.executeDistributed(cfg, addrs, agentFlags{token: *agentToken, ca: *agentCA}); err != nil {
+			return err
+		}
+	} else {
+		eng, err := engine.New(cfg, engine.Options{Log: c.stderr})
+		if err != nil {
+			return err
+		}
+		if results, err = eng.Run(context.Background()); err != nil {
+			return err
+		}
 	}
-	fmt.Fprintf(c.stderr, "RPS: %d\n", cfg.LoadTest.RPS)
-	fmt.Fprintf(c.stderr, "Concurrency: %d\n", cfg.LoadTest.Concurrency)
-	fmt.Fprintf(c.stderr, "Duration: %ds\n", cfg.LoadTest.Duration)
-	fmt.Fprintf(c.stderr, "\n")
-
-	// Run load test
-	results := c.executeLoadTest(targets, cfg.LoadTest.Endpoints, cfg.LoadTest.RPS, cfg.LoadTest.Concurrency, cfg.LoadTest.Duration)
+	results.Redact(cfg.Secrets.Mask)
 
 	// Generate report
 	switch cfg.LoadTest.Output {
@@ -114,124 +124,90 @@ Global Flags:
 	}
 }
 
-func (c *CLI) executeLoadTest(urls []string, endpoints []config.Endpoint, rps, concurrency, duration int) *report.Results {
-	results := &report.Results{
-		URLs:        urls,
-		RPS:         rps,
-		Concurrency: concurrency,
-		Duration:    duration,
-		StartTime:   time.Now(),
-		Requests:    make([]report.RequestResult, 0),
-	}
+// overrideFlags are the flags shared by commands that resolve the effective
+// configuration from the config file and command-line overrides.
+type overrideFlags struct {
+	configFile  *string
+	rps         *int
+	concurrency *int
+	output      *string
+	outputShort *string
+	replayFile  *string
+	speed       *float64
+	searchMax   *int
+	// seed is nil when --seed is not given, so that --seed 0 is honoured.
+	seed *int64
+}
 
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+// overrideFlagsUsage describes the flags of addOverrideFlags other than
+// --config, for the usage texts of the commands that share them.
+const overrideFlagsUsage = `  --rps int              requests per second (overrides config)
+  --concurrency int      number of concurrent clients (overrides config)
+  -o, --output string    output format: html, json (overrides config)
+  --replay string        replay requests from an nginx/Apache combined access
+                         log or NDJSON request log (sets mode to replay)
+  --speed float          replay speed factor for original timing (overrides config)
+  --search-max int       search for the highest rate up to this RPS that meets
+                         the SLO (sets mode to search)
+  --seed int             seed of the random choices, for reproducible runs
+                         (overrides config)
+`
 
-	// Calculate interval between requests
-	interval := time.Second / time.Duration(rps)
-	totalRequests := rps * duration
+func addOverrideFlags(fs *flag.FlagSet) *overrideFlags {
+	o := &overrideFlags{
+		configFile:  fs.String("config", "", "config file (default is ./config.yaml)"),
+		rps:         fs.Int("rps", 0, "requests per second (overrides config)"),
+		concurrency: fs.Int("concurrency", 0, "number of concurrent clients (overrides config)"),
+		output:      fs.String("output", "", "output format: html, json (overrides config)"),
+		outputShort: fs.String("o", "", "output format: html, json (overrides config)"),
+		replayFile:  fs.String("replay", "", "replay requests from an access log or NDJSON request log"),
+		speed:       fs.Float64("speed", 0, "replay speed factor for original timing (overrides config)"),
+		searchMax:   fs.Int("search-max", 0, "search for the highest rate up to this RPS that meets the SLO (sets mode to search)"),
+	}
+	fs.Func("seed", "seed of the random choices, for reproducible runs (overrides config)", func(value string) error {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q", value)
+		}
+		o.seed = &seed
+		return nil
+	})
+	return o
+}
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// load reads the config file and applies command-line flags on top of it.
+func (o *overrideFlags) load() (*config.Config, error) {
+	cfg, err := config.LoadConfig(*o.configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Build weighted URL selector
-	type weightedURL struct {
-		url    string
-		weight float64
+	// Override config with command-line flags
+	if *o.rps > 0 {
+		cfg.LoadTest.RPS = *o.rps
 	}
-	weightedURLs := make([]weightedURL, 0)
-	totalWeight := 0.0
-	for i, ep := range endpoints {
-		weight := ep.Weight
-		if weight <= 0 {
-			weight = 1.0
-		}
-		weightedURLs = append(weightedURLs, weightedURL{url: urls[i], weight: weight})
-		totalWeight += weight
+	if *o.concurrency > 0 {
+		cfg.LoadTest.Concurrency = *o.concurrency
 	}
-
-	// Normalize weights
-	for i := range weightedURLs {
-		weightedURLs[i].weight /= totalWeight
+	if *o.output != "" {
+		cfg.LoadTest.Output = *o.output
+	} else if *o.outputShort != "" {
+		cfg.LoadTest.Output = *o.outputShort
 	}
-
-	// Function to select URL based on weight
-	selectURL := func() string {
-		r := rand.Float64()
-		cumulative := 0.0
-		for _, wu := range weightedURLs {
-			cumulative += wu.weight
-			if r <= cumulative {
-				return wu.url
-			}
-		}
-		return weightedURLs[len(weightedURLs)-1].url
-	}
-
-	// Channel to distribute work
-	workChan := make(chan string, totalRequests)
-
-	// Start workers
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for url := range workChan {
-				start := time.Now()
-				resp, err := client.Get(url)
-				elapsed := time.Since(start)
-
-				result := report.RequestResult{
-					Timestamp:  start,
-					Duration:   elapsed,
-					StatusCode: 0,
-					Error:      "",
-					URL:        url,
-				}
-
-				if err != nil {
-					result.Error = err.Error()
-				} else {
-					result.StatusCode = resp.StatusCode
-					io.Copy(io.Discard, resp.Body)
-					resp.Body.Close()
-				}
-
-				mu.Lock()
-				results.Requests = append(results.Requests, result)
-				mu.Unlock()
-			}
-		}()
-	}
-
-	// Send requests at specified rate
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	requestCount := 0
-	timeout := time.After(time.Duration(duration) * time.Second)
-
-	for {
-		select {
-		case <-timeout:
-			close(workChan)
-			wg.Wait()
-			results.EndTime = time.Now()
-			return results
-		case <-ticker.C:
-			if requestCount < totalRequests {
-				workChan <- selectURL()
-				requestCount++
-			} else {
-				close(workChan)
-				wg.Wait()
-				results.EndTime = time.Now()
-				return results
-			}
-		}
+	if *o.replayFile != "" {
+		cfg.LoadTest.Mode = config.ModeReplay
+		cfg.LoadTest.Replay.File = *o.replayFile
+	}
+	if *o.speed > 0 {
+		cfg.LoadTest.Replay.Speed = *o.speed
+	}
+	if *o.searchMax > 0 {
+		cfg.LoadTest.Mode = config.ModeSearch
+		cfg.LoadTest.Search.MaxRPS = *o.searchMax
+	}
+	if o.seed != nil {
+		cfg.LoadTest.Seed = o.seed
 	}
-}
-
 
+	return cfg, nil
+}
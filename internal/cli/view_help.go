@@ -13,6 +13,8 @@ Usage:
 Available Commands:
   run         Run load test against target endpoint
   config      Manage configuration files
+  record      Record proxied traffic into a configuration file
+  agent       Run a load generation agent for distributed tests
   version     Print the version information
   help        Help about any command
 
@@ -34,6 +36,10 @@ func (c *CLI) helpCommand(command string) error {
 	case "config":
 		c.printConfigUsage()
 		return nil
+	case "record":
+		return c.recordCommand([]string{"--help"})
+	case "agent":
+		return c.agentCommand([]string{"--help"})
 	case "version":
 		return c.versionCommand([]string{"--help"})
 	default:
@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// request is a single observed request before it is turned into an
+// endpoint.
+type request struct {
+	method  string
+	url     string
+	headers map[string]string
+	body    string
+}
+
+// skippedHeaders are managed by the HTTP client and must not be replayed.
+var skippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"accept-encoding":   true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"te":                true,
+	"trailer":           true,
+	"proxy-connection":  true,
+}
+
+var staticExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true, ".avif": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true,
+}
+
+// isStaticAsset reports whether the URL or response content type looks like
+// a static asset rather than an API or page request.
+func isStaticAsset(u *url.URL, contentType string) bool {
+	if staticExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"image/", "font/", "video/", "audio/", "text/css", "application/javascript", "text/javascript"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildConfig groups identical requests (same method, path and body) into
+// one endpoint weighted by how often it was seen. Only requests to domain
+// are kept; when domain is empty the most frequent origin is used.
+//
+// With templateIDs, ID-like path segments are replaced by ${NAME_ID:-value}
+// references defaulting to the first value seen, and requests are grouped
+// by method, templated path and query parameter names instead.
+func buildConfig(requests []request, domain string, templateIDs bool) (*config.Config, error) {
+	type parsed struct {
+		request
+		origin string
+		target string
+		url    *url.URL
+	}
+
+	all := make([]parsed, 0, len(requests))
+	originCounts := make(map[string]int)
+	var origins []string
+	for _, r := range requests {
+		u, err := url.Parse(r.url)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if originCounts[origin] == 0 {
+			origins = append(origins, origin)
+		}
+		originCounts[origin]++
+		all = append(all, parsed{request: r, origin: origin, target: u.RequestURI(), url: u})
+	}
+
+	if domain == "" {
+		for _, origin := range origins {
+			if originCounts[origin] > originCounts[domain] {
+				domain = origin
+			}
+		}
+	}
+	domain = strings.TrimSuffix(domain, "/")
+	if domain == "" {
+		return nil, fmt.Errorf("no requests found")
+	}
+
+	cfg := config.Default()
+	cfg.LoadTest.Domain = domain
+	cfg.LoadTest.Endpoints = nil
+
+	index := make(map[string]int)
+	for _, r := range all {
+		if !matchesDomain(r.origin, domain) {
+			continue
+		}
+		// A bare host given as domain takes the scheme of the first match.
+		cfg.LoadTest.Domain = r.origin
+		key := r.method + " " + r.target + "\x00" + r.body
+		path := escape(r.target)
+		if templateIDs {
+			var pattern string
+			pattern, path = templatePath(r.url)
+			key = r.method + " " + pattern
+		}
+		if i, ok := index[key]; ok {
+			cfg.LoadTest.Endpoints[i].Weight++
+			continue
+		}
+		index[key] = len(cfg.LoadTest.Endpoints)
+		cfg.LoadTest.Endpoints = append(cfg.LoadTest.Endpoints, config.Endpoint{
+			Method:  r.method,
+			Path:    path,
+			Headers: escapeHeaders(r.headers),
+			Body:    escape(r.body),
+			Weight:  1,
+		})
+	}
+
+	if len(cfg.LoadTest.Endpoints) == 0 {
+		return nil, fmt.Errorf("no requests to %s found", domain)
+	}
+	return cfg, nil
+}
+
+// matchesDomain accepts either a full origin or a bare host as domain.
+func matchesDomain(origin, domain string) bool {
+	if strings.Contains(domain, "://") {
+		return origin == domain
+	}
+	_, host, _ := strings.Cut(origin, "://")
+	return host == domain
+}
+
+// escape protects a captured value from config interpolation and from being
+// read as a request template.
+func escape(value string) string {
+	return config.Escape(config.EscapeTemplate(value))
+}
+
+func escapeHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	escaped := make(map[string]string, len(headers))
+	for name, value := range headers {
+		escaped[name] = escape(value)
+	}
+	return escaped
+}
+
+var (
+	uuidPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numericPattern = regexp.MustCompile(`^\d+$`)
+	hexPattern     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	tokenPattern   = regexp.MustCompile(`^[0-9A-Za-z_-]{20,}$`)
+	nonWordPattern = regexp.MustCompile(`[^0-9A-Za-z]+`)
+)
+
+// isID reports whether a path segment looks like an identifier rather than
+// a fixed part of the route.
+func isID(segment string) bool {
+	switch {
+	case numericPattern.MatchString(segment), uuidPattern.MatchString(segment), hexPattern.MatchString(segment):
+		return true
+	case tokenPattern.MatchString(segment):
+		// Long opaque tokens mix letters and digits; long words do not.
+		return strings.ContainsAny(segment, "0123456789")
+	}
+	return false
+}
+
+// templatePath returns the route pattern of u, used for grouping, and the
+// endpoint path with ID segments replaced by ${NAME_ID:-value} references.
+func templatePath(u *url.URL) (pattern, path string) {
+	segments := strings.Split(u.EscapedPath(), "/")
+	patterns := make([]string, len(segments))
+	used := make(map[string]int)
+	for i, segment := range segments {
+		patterns[i] = segment
+		segments[i] = escape(segment)
+		if !isID(segment) {
+			continue
+		}
+
+		name := "ID"
+		if i > 0 && segments[i-1] != "" && !strings.HasPrefix(segments[i-1], "${") {
+			name = strings.ToUpper(strings.Trim(nonWordPattern.ReplaceAllString(segments[i-1], "_"), "_")) + "_ID"
+		}
+		used[name]++
+		if n := used[name]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		patterns[i] = "{" + strings.ToLower(name) + "}"
+		segments[i] = "${" + name + ":-" + segment + "}"
+	}
+
+	pattern = strings.Join(patterns, "/")
+	path = strings.Join(segments, "/")
+
+	if u.RawQuery != "" {
+		path += "?" + escape(u.RawQuery)
+		keys := make([]string, 0)
+		for k := range u.Query() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pattern += "?" + strings.Join(keys, "&")
+	}
+	return pattern, path
+}
@@ -0,0 +1,345 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// CurlOptions controls which curl commands are imported.
+type CurlOptions struct {
+	// Domain keeps only requests to this origin or host. When empty the most
+	// frequent origin among the commands is used.
+	Domain string
+	// IncludeStatic keeps requests for scripts, stylesheets, images, fonts
+	// and media.
+	IncludeStatic bool
+	// SkipCookies drops cookies given with -b/--cookie.
+	SkipCookies bool
+}
+
+// Curl converts one or more curl command lines, as copied from browser dev
+// tools or shared by developers, into endpoints. Commands may span several
+// lines with trailing backslashes; identical requests are merged and
+// weighted by how often they appear.
+func Curl(text string, opts CurlOptions) (*config.Config, error) {
+	commands, err := splitCurlCommands(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("no curl commands found")
+	}
+
+	requests := make([]request, 0, len(commands))
+	for i, args := range commands {
+		r, err := parseCurl(args, opts)
+		if err != nil {
+			return nil, fmt.Errorf("curl command %d: %w", i+1, err)
+		}
+		u, err := url.Parse(r.url)
+		if err != nil {
+			return nil, fmt.Errorf("curl command %d: invalid URL %q: %w", i+1, r.url, err)
+		}
+		if !opts.IncludeStatic && isStaticAsset(u, "") {
+			continue
+		}
+		requests = append(requests, r)
+	}
+
+	return buildConfig(requests, opts.Domain, false)
+}
+
+// splitCurlCommands tokenizes text with POSIX shell quoting rules and splits
+// it into curl invocations.
+func splitCurlCommands(text string) ([][]string, error) {
+	var commands [][]string
+	var current []string
+	var word strings.Builder
+	inWord := false
+
+	flushWord := func() {
+		if inWord {
+			current = append(current, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	flushCommand := func() {
+		flushWord()
+		if len(current) > 0 {
+			commands = append(commands, current)
+			current = nil
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && (runes[i+1] == '\n' || runes[i+1] == '\r'):
+			// Line continuation.
+			i++
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+			}
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			// ANSI-C quoting as produced by Chrome's "Copy as cURL".
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					word.WriteString(unescapeANSIC(runes[i]))
+					continue
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case r == '\n' || r == ';':
+			flushCommand()
+		case r == ' ' || r == '\t' || r == '\r':
+			flushWord()
+		case r == '#' && !inWord:
+			// Comment until end of line.
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	flushCommand()
+
+	curls := make([][]string, 0, len(commands))
+	for _, cmd := range commands {
+		if len(cmd) > 0 && (cmd[0] == "curl" || strings.HasSuffix(cmd[0], "/curl")) {
+			curls = append(curls, cmd[1:])
+		}
+	}
+	return curls, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeANSIC(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	}
+	return string(r)
+}
+
+// curlFlagsWithValue are curl options that take an argument but do not
+// affect the generated request.
+var curlFlagsWithValue = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-w": true, "--write-out": true, "-x": true, "--proxy": true, "--retry": true,
+	"--cacert": true, "--cert": true, "--key": true, "-c": true, "--cookie-jar": true,
+	"--resolve": true, "--limit-rate": true, "-T": true, "--upload-file": true,
+}
+
+func parseCurl(args []string, opts CurlOptions) (request, error) {
+	r := request{headers: make(map[string]string)}
+	var data []string
+	var cookies []string
+	method := ""
+	getData := false
+
+	next := func(i *int, flag string) (string, error) {
+		*i++
+		if *i >= len(args) {
+			return "", fmt.Errorf("missing value for %s", flag)
+		}
+		return args[*i], nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, inline, hasInline := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			name, inline, hasInline = strings.Cut(arg, "=")
+		} else if len(arg) > 2 && arg[0] == '-' && strings.ContainsRune("XHdbuAe", rune(arg[1])) {
+			// Short options may be glued to their value, e.g. -XPOST.
+			name, inline, hasInline = arg[:2], arg[2:], true
+		}
+		value := func() (string, error) {
+			if hasInline {
+				return inline, nil
+			}
+			return next(&i, name)
+		}
+
+		var err error
+		var v string
+		switch name {
+		case "-X", "--request":
+			if method, err = value(); err != nil {
+				return r, err
+			}
+		case "-H", "--header":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			header, hv, _ := strings.Cut(v, ":")
+			header = strings.TrimSpace(header)
+			hv = strings.TrimSpace(hv)
+			switch lower := strings.ToLower(header); {
+			case lower == "cookie":
+				cookies = append(cookies, hv)
+			case !skippedHeaders[lower]:
+				r.headers[header] = hv
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			data = append(data, v)
+		case "--data-urlencode":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			key, val, ok := strings.Cut(v, "=")
+			if ok {
+				data = append(data, url.QueryEscape(key)+"="+url.QueryEscape(val))
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+		case "--json":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			data = append(data, v)
+			r.headers["Content-Type"] = "application/json"
+			r.headers["Accept"] = "application/json"
+		case "-b", "--cookie":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			if strings.Contains(v, "=") {
+				cookies = append(cookies, v)
+			}
+		case "-u", "--user":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			r.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(v))
+		case "-A", "--user-agent":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			r.headers["User-Agent"] = v
+		case "-e", "--referer":
+			if v, err = value(); err != nil {
+				return r, err
+			}
+			r.headers["Referer"] = v
+		case "--url":
+			if r.url, err = value(); err != nil {
+				return r, err
+			}
+		case "-G", "--get":
+			getData = true
+		case "-I", "--head":
+			method = "HEAD"
+		default:
+			if curlFlagsWithValue[name] {
+				if !hasInline {
+					i++
+				}
+				continue
+			}
+			if strings.HasPrefix(arg, "-") {
+				// Flags such as --compressed, -s, -k, -L and -v do not change the request.
+				continue
+			}
+			if r.url == "" {
+				r.url = arg
+			}
+		}
+	}
+
+	if r.url == "" {
+		return r, fmt.Errorf("no URL given")
+	}
+	if !strings.Contains(r.url, "://") {
+		r.url = "http://" + r.url
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case getData && body != "":
+		sep := "?"
+		if strings.Contains(r.url, "?") {
+			sep = "&"
+		}
+		r.url += sep + body
+	case body != "":
+		r.body = body
+		if !hasHeader(r.headers, "Content-Type") {
+			r.headers["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+		if method == "" {
+			method = "POST"
+		}
+	}
+	if method == "" {
+		method = "GET"
+	}
+	r.method = strings.ToUpper(method)
+
+	if !opts.SkipCookies && len(cookies) > 0 {
+		r.headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+	return r, nil
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// HAROptions controls which HAR entries are imported.
+type HAROptions struct {
+	// Domain keeps only requests to this origin or host. When empty the most
+	// frequent origin in the archive is used.
+	Domain string
+	// IncludeStatic keeps requests for scripts, stylesheets, images, fonts
+	// and media.
+	IncludeStatic bool
+	// SkipCookies drops the cookies sent with each request.
+	SkipCookies bool
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		Cookies  []harNameValue `json:"cookies"`
+		PostData *harPostData   `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params"`
+}
+
+// HAR converts the entries of an HTTP Archive into endpoints weighted by how
+// often each request appears.
+func HAR(data []byte, opts HAROptions) (*config.Config, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file: %w", err)
+	}
+
+	requests := make([]request, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if !opts.IncludeStatic && isStaticAsset(u, entry.Response.Content.MimeType) {
+			continue
+		}
+
+		r := request{
+			method:  strings.ToUpper(entry.Request.Method),
+			url:     entry.Request.URL,
+			headers: make(map[string]string),
+		}
+		for _, h := range entry.Request.Headers {
+			name := strings.ToLower(h.Name)
+			if strings.HasPrefix(name, ":") || skippedHeaders[name] || name == "cookie" {
+				continue
+			}
+			r.headers[h.Name] = h.Value
+		}
+		if !opts.SkipCookies && len(entry.Request.Cookies) > 0 {
+			cookies := make([]string, len(entry.Request.Cookies))
+			for i, c := range entry.Request.Cookies {
+				cookies[i] = c.Name + "=" + c.Value
+			}
+			r.headers["Cookie"] = strings.Join(cookies, "; ")
+		}
+		if pd := entry.Request.PostData; pd != nil {
+			r.body = pd.Text
+			if r.body == "" && len(pd.Params) > 0 {
+				form := url.Values{}
+				for _, p := range pd.Params {
+					form.Add(p.Name, p.Value)
+				}
+				r.body = form.Encode()
+			}
+		}
+		requests = append(requests, r)
+	}
+
+	return buildConfig(requests, opts.Domain, false)
+}
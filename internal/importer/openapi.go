@@ -0,0 +1,462 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// OpenAPIOptions controls which operations are imported and how they are
+// weighted.
+type OpenAPIOptions struct {
+	// Domain overrides the first server URL of the spec.
+	Domain string
+	// Tags keeps only operations with at least one of these tags.
+	Tags []string
+	// OperationIDs keeps only operations with one of these IDs.
+	OperationIDs []string
+	// Weights assigns weights by operationId or "METHOD /path".
+	Weights map[string]float64
+	// DefaultWeight is used for operations without an explicit weight.
+	DefaultWeight float64
+}
+
+type openAPISpec struct {
+	OpenAPI    string                 `yaml:"openapi"`
+	Servers    []openAPIServer        `yaml:"servers"`
+	Paths      map[string]openAPIPath `yaml:"paths"`
+	Components openAPIComponents      `yaml:"components"`
+}
+
+type openAPIServer struct {
+	URL       string `yaml:"url"`
+	Variables map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"variables"`
+}
+
+type openAPIComponents struct {
+	Schemas       map[string]*openAPISchema      `yaml:"schemas"`
+	Parameters    map[string]*openAPIParameter   `yaml:"parameters"`
+	RequestBodies map[string]*openAPIRequestBody `yaml:"requestBodies"`
+}
+
+type openAPIPath struct {
+	Parameters []*openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation   `yaml:"get"`
+	Put        *openAPIOperation   `yaml:"put"`
+	Post       *openAPIOperation   `yaml:"post"`
+	Delete     *openAPIOperation   `yaml:"delete"`
+	Options    *openAPIOperation   `yaml:"options"`
+	Head       *openAPIOperation   `yaml:"head"`
+	Patch      *openAPIOperation   `yaml:"patch"`
+}
+
+type openAPIOperation struct {
+	OperationID string              `yaml:"operationId"`
+	Tags        []string            `yaml:"tags"`
+	Parameters  []*openAPIParameter `yaml:"parameters"`
+	RequestBody *openAPIRequestBody `yaml:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref      string                  `yaml:"$ref"`
+	Name     string                  `yaml:"name"`
+	In       string                  `yaml:"in"`
+	Required bool                    `yaml:"required"`
+	Schema   *openAPISchema          `yaml:"schema"`
+	Example  any                     `yaml:"example"`
+	Examples map[string]openAPIValue `yaml:"examples"`
+}
+
+type openAPIRequestBody struct {
+	Ref     string                      `yaml:"$ref"`
+	Content map[string]openAPIMediaType `yaml:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema          `yaml:"schema"`
+	Example  any                     `yaml:"example"`
+	Examples map[string]openAPIValue `yaml:"examples"`
+}
+
+type openAPIValue struct {
+	Value any `yaml:"value"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `yaml:"$ref"`
+	Type       string                    `yaml:"type"`
+	Format     string                    `yaml:"format"`
+	Properties map[string]*openAPISchema `yaml:"properties"`
+	Items      *openAPISchema            `yaml:"items"`
+	AllOf      []*openAPISchema          `yaml:"allOf"`
+	OneOf      []*openAPISchema          `yaml:"oneOf"`
+	AnyOf      []*openAPISchema          `yaml:"anyOf"`
+	Enum       []any                     `yaml:"enum"`
+	Example    any                       `yaml:"example"`
+	Default    any                       `yaml:"default"`
+}
+
+// OpenAPI converts an OpenAPI 3 document (YAML or JSON) into a config with
+// one endpoint per operation.
+func OpenAPI(data []byte, opts OpenAPIOptions) (*config.Config, error) {
+	var spec openAPISpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q (only 3.x is supported)", spec.OpenAPI)
+	}
+
+	cfg := config.Default()
+	cfg.LoadTest.Domain = opts.Domain
+	if cfg.LoadTest.Domain == "" {
+		cfg.LoadTest.Domain = spec.serverURL()
+	}
+	cfg.LoadTest.Endpoints = nil
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, op := range item.operations() {
+			if !opts.includes(op.operation) {
+				continue
+			}
+			endpoint, err := spec.endpoint(path, op.method, item.Parameters, op.operation)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.method, path, err)
+			}
+			endpoint.Weight = opts.weight(op.operation.OperationID, op.method+" "+path)
+			endpoint = escapeEndpoint(endpoint)
+			cfg.LoadTest.Endpoints = append(cfg.LoadTest.Endpoints, endpoint)
+		}
+	}
+
+	if len(cfg.LoadTest.Endpoints) == 0 {
+		return nil, fmt.Errorf("no operations matched the given filters")
+	}
+	return cfg, nil
+}
+
+type methodOperation struct {
+	method    string
+	operation *openAPIOperation
+}
+
+func (p openAPIPath) operations() []methodOperation {
+	all := []methodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch},
+		{"DELETE", p.Delete}, {"HEAD", p.Head}, {"OPTIONS", p.Options},
+	}
+	ops := make([]methodOperation, 0, len(all))
+	for _, op := range all {
+		if op.operation != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func (o OpenAPIOptions) includes(op *openAPIOperation) bool {
+	if len(o.OperationIDs) > 0 && !containsString(o.OperationIDs, op.OperationID) {
+		return false
+	}
+	if len(o.Tags) > 0 {
+		for _, tag := range op.Tags {
+			if containsString(o.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (o OpenAPIOptions) weight(keys ...string) float64 {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if w, ok := o.Weights[key]; ok {
+			return w
+		}
+	}
+	if o.DefaultWeight > 0 {
+		return o.DefaultWeight
+	}
+	return 1.0
+}
+
+func (s *openAPISpec) serverURL() string {
+	if len(s.Servers) == 0 {
+		return config.Default().LoadTest.Domain
+	}
+	server := s.Servers[0]
+	u := server.URL
+	for name, variable := range server.Variables {
+		u = strings.ReplaceAll(u, "{"+name+"}", variable.Default)
+	}
+	if strings.HasPrefix(u, "/") {
+		u = config.Default().LoadTest.Domain + u
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+func (s *openAPISpec) endpoint(path, method string, shared []*openAPIParameter, op *openAPIOperation) (config.Endpoint, error) {
+	endpoint := config.Endpoint{Method: method}
+
+	// Operation parameters override path-level ones with the same name and location.
+	params := make(map[string]*openAPIParameter)
+	var order []string
+	for _, p := range append(append([]*openAPIParameter{}, shared...), op.Parameters...) {
+		p = s.resolveParameter(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	query := url.Values{}
+	for _, key := range order {
+		p := params[key]
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(s.parameterValue(p)))
+		case "query":
+			if p.Required || p.Example != nil {
+				query.Add(p.Name, s.parameterValue(p))
+			}
+		case "header":
+			if p.Required {
+				if endpoint.Headers == nil {
+					endpoint.Headers = make(map[string]string)
+				}
+				endpoint.Headers[p.Name] = s.parameterValue(p)
+			}
+		}
+	}
+	endpoint.Path = path
+	if len(query) > 0 {
+		endpoint.Path += "?" + query.Encode()
+	}
+
+	if body := s.resolveRequestBody(op.RequestBody); body != nil {
+		contentType, payload, err := s.requestBodyExample(body)
+		if err != nil {
+			return endpoint, err
+		}
+		if contentType != "" {
+			if endpoint.Headers == nil {
+				endpoint.Headers = make(map[string]string)
+			}
+			endpoint.Headers["Content-Type"] = contentType
+			endpoint.Body = payload
+		}
+	}
+
+	return endpoint, nil
+}
+
+func (s *openAPISpec) resolveParameter(p *openAPIParameter) *openAPIParameter {
+	if p != nil && p.Ref != "" {
+		return s.Components.Parameters[refName(p.Ref)]
+	}
+	return p
+}
+
+func (s *openAPISpec) resolveRequestBody(b *openAPIRequestBody) *openAPIRequestBody {
+	if b != nil && b.Ref != "" {
+		return s.Components.RequestBodies[refName(b.Ref)]
+	}
+	return b
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// parameterValue returns the example value of a parameter, or a
+// placeholder derived from its schema.
+func (s *openAPISpec) parameterValue(p *openAPIParameter) string {
+	if p.Example != nil {
+		return fmt.Sprint(p.Example)
+	}
+	if v, ok := firstExample(p.Examples); ok {
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprint(s.example(p.Schema, make(map[string]bool)))
+}
+
+// requestBodyExample picks the JSON media type when available and returns
+// its content type and example payload.
+func (s *openAPISpec) requestBodyExample(body *openAPIRequestBody) (string, string, error) {
+	contentTypes := make([]string, 0, len(body.Content))
+	for ct := range body.Content {
+		contentTypes = append(contentTypes, ct)
+	}
+	sort.Strings(contentTypes)
+	if len(contentTypes) == 0 {
+		return "", "", nil
+	}
+
+	contentType := contentTypes[0]
+	for _, ct := range contentTypes {
+		if strings.Contains(ct, "json") {
+			contentType = ct
+			break
+		}
+	}
+
+	media := body.Content[contentType]
+	value := media.Example
+	if value == nil {
+		value, _ = firstExample(media.Examples)
+	}
+	if value == nil {
+		value = s.example(media.Schema, make(map[string]bool))
+	}
+
+	if str, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return contentType, str, nil
+	}
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode example request body: %w", err)
+	}
+	return contentType, string(payload), nil
+}
+
+func firstExample(examples map[string]openAPIValue) (any, bool) {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := examples[name].Value; v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// example builds an example value for schema, preferring explicit examples,
+// defaults and enum values over generated placeholders. expanding holds the
+// names of the referenced schemas being expanded; a reference back to one of
+// them is recursive and becomes null, or an empty array for array items.
+func (s *openAPISpec) example(schema *openAPISchema, expanding map[string]bool) any {
+	for schema != nil && schema.Ref != "" {
+		name := refName(schema.Ref)
+		if expanding[name] {
+			return nil
+		}
+		expanding[name] = true
+		defer delete(expanding, name)
+		schema = s.Components.Schemas[name]
+	}
+	if schema == nil {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]any)
+		for _, sub := range schema.AllOf {
+			if obj, ok := s.example(sub, expanding).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return s.example(schema.OneOf[0], expanding)
+	case len(schema.AnyOf) > 0:
+		return s.example(schema.AnyOf[0], expanding)
+	}
+
+	switch schema.Type {
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "array":
+		item := s.example(schema.Items, expanding)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+	case "string":
+		return stringPlaceholder(schema.Format)
+	}
+
+	if schema.Type == "object" || len(schema.Properties) > 0 {
+		obj := make(map[string]any, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			obj[name] = s.example(prop, expanding)
+		}
+		return obj
+	}
+	return nil
+}
+
+func stringPlaceholder(format string) string {
+	switch format {
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000001"
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	}
+	return "example"
+}
+
+// escapeEndpoint protects generated values from config interpolation and
+// request templates.
+func escapeEndpoint(e config.Endpoint) config.Endpoint {
+	e.Path = escape(e.Path)
+	e.Body = escape(e.Body)
+	for name, value := range e.Headers {
+		e.Headers[name] = escape(value)
+	}
+	return e
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kitsystemyou/meteor-shower/config"
+)
+
+// RecordOptions controls which proxied requests are recorded.
+type RecordOptions struct {
+	// IncludeStatic keeps requests for scripts, stylesheets, images, fonts
+	// and media.
+	IncludeStatic bool
+	// SkipCookies drops the Cookie header.
+	SkipCookies bool
+}
+
+// Recorder collects requests observed by a proxy. It is safe for concurrent
+// use.
+type Recorder struct {
+	opts RecordOptions
+
+	mu       sync.Mutex
+	requests []request
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder(opts RecordOptions) *Recorder {
+	return &Recorder{opts: opts}
+}
+
+// Record adds a request forwarded to target, an origin such as
+// "http://svc:8080". body is the request body and contentType the content
+// type of the response, used to detect static assets.
+func (r *Recorder) Record(target string, req *http.Request, body []byte, contentType string) {
+	if !r.opts.IncludeStatic && isStaticAsset(req.URL, contentType) {
+		return
+	}
+
+	headers := make(map[string]string)
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if skippedHeaders[lower] || strings.HasPrefix(lower, "x-forwarded-") || (r.opts.SkipCookies && lower == "cookie") {
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request{
+		method:  req.Method,
+		url:     strings.TrimSuffix(target, "/") + req.URL.RequestURI(),
+		headers: headers,
+		body:    string(body),
+	})
+}
+
+// Len returns the number of recorded requests.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// Config turns the recorded requests into endpoints for domain. Requests
+// to the same route are merged and weighted by frequency; ID-like path
+// segments become ${NAME_ID:-value} references so they can be overridden
+// from the environment.
+func (r *Recorder) Config(domain string) (*config.Config, error) {
+	r.mu.Lock()
+	requests := append([]request(nil), r.requests...)
+	r.mu.Unlock()
+
+	return buildConfig(requests, domain, true)
+}
@@ -0,0 +1,196 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported request log formats.
+const (
+	FormatCombined = "combined"
+	FormatNDJSON   = "ndjson"
+)
+
+// Entry is a single logged request.
+type Entry struct {
+	// Offset is the time since the first request in the log.
+	Offset  time.Duration
+	Method  string
+	Path    string
+	Headers map[string]string
+	Body    string
+}
+
+// Load reads the request log at path. An empty format is detected from the
+// first non-empty line.
+func Load(path, format string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request log: %w", err)
+	}
+	defer f.Close()
+
+	return Parse(f, format)
+}
+
+// Parse reads nginx/Apache combined access log lines or NDJSON request
+// records and returns the entries ordered by time.
+func Parse(r io.Reader, format string) ([]Entry, error) {
+	type timedEntry struct {
+		Entry
+		time time.Time
+	}
+
+	var entries []timedEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if format == "" {
+			format = detectFormat(line)
+		}
+
+		var (
+			e   Entry
+			t   time.Time
+			err error
+		)
+		switch format {
+		case FormatCombined:
+			e, t, err = parseCombined(string(line))
+		case FormatNDJSON:
+			e, t, err = parseNDJSON(line)
+		default:
+			return nil, fmt.Errorf("unsupported request log format %q", format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		entries = append(entries, timedEntry{Entry: e, time: t})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request log: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("request log is empty")
+	}
+
+	// Access logs are written in completion order, so requests can be
+	// slightly out of order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+
+	first := entries[0].time
+	result := make([]Entry, len(entries))
+	for i, e := range entries {
+		e.Offset = e.time.Sub(first)
+		result[i] = e.Entry
+	}
+	return result, nil
+}
+
+func detectFormat(line []byte) string {
+	if line[0] == '{' {
+		return FormatNDJSON
+	}
+	return FormatCombined
+}
+
+// combinedPattern matches the Common and Combined Log Formats:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.0" 200 2326 "referer" "user-agent"
+var combinedPattern = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)(?: [^"]*)?" \d{3} \S+(?: "(?:[^"\\]|\\.)*" "((?:[^"\\]|\\.)*)")?`)
+
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+func parseCombined(line string) (Entry, time.Time, error) {
+	m := combinedPattern.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, time.Time{}, fmt.Errorf("not a combined log line")
+	}
+	t, err := time.Parse(combinedTimeLayout, m[1])
+	if err != nil {
+		return Entry{}, time.Time{}, fmt.Errorf("invalid timestamp %q: %w", m[1], err)
+	}
+
+	e := Entry{Method: m[2], Path: m[3]}
+	if ua := m[4]; ua != "" && ua != "-" {
+		e.Headers = map[string]string{"User-Agent": ua}
+	}
+	return e, t, nil
+}
+
+type ndjsonRecord struct {
+	Timestamp string            `json:"timestamp"`
+	Time      string            `json:"time"`
+	OffsetMs  *float64          `json:"offset_ms"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+}
+
+var epoch = time.Unix(0, 0)
+
+func parseNDJSON(line []byte) (Entry, time.Time, error) {
+	var rec ndjsonRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return Entry{}, time.Time{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var t time.Time
+	switch ts := firstNonEmpty(rec.Timestamp, rec.Time); {
+	case rec.OffsetMs != nil:
+		t = epoch.Add(time.Duration(*rec.OffsetMs * float64(time.Millisecond)))
+	case ts != "":
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return Entry{}, time.Time{}, fmt.Errorf("invalid timestamp %q: %w", ts, err)
+		}
+	default:
+		return Entry{}, time.Time{}, fmt.Errorf("one of timestamp, time or offset_ms is required")
+	}
+
+	path := rec.Path
+	if path == "" && rec.URL != "" {
+		u, err := url.Parse(rec.URL)
+		if err != nil {
+			return Entry{}, time.Time{}, fmt.Errorf("invalid url %q: %w", rec.URL, err)
+		}
+		path = u.RequestURI()
+	}
+	if path == "" {
+		return Entry{}, time.Time{}, fmt.Errorf("path or url is required")
+	}
+
+	method := strings.ToUpper(rec.Method)
+	if method == "" {
+		method = "GET"
+	}
+	return Entry{Method: method, Path: path, Headers: rec.Headers, Body: rec.Body}, t, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
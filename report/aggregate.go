@@ -0,0 +1,238 @@
+package report
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// histogramGrowth is the ratio between the bounds of consecutive histogram
+// buckets, so percentiles read from a Histogram are within 1% of the exact
+// value.
+const histogramGrowth = 1.01
+
+var logHistogramGrowth = math.Log(histogramGrowth)
+
+// Histogram counts request durations in logarithmic buckets. Unlike a list
+// of durations its size does not grow with the number of requests, and
+// histograms from several load generators can be merged.
+type Histogram struct {
+	// Counts maps a bucket index to the number of durations in it. Bucket i
+	// holds durations from histogramGrowth^i up to histogramGrowth^(i+1)
+	// microseconds; bucket 0 also holds everything below 1µs.
+	Counts map[int]int
+	Total  int
+}
+
+// NewHistogram returns an empty Histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{Counts: make(map[int]int)}
+}
+
+// Record adds one duration.
+func (h *Histogram) Record(d time.Duration) {
+	us := float64(d) / float64(time.Microsecond)
+	bucket := 0
+	if us > 1 {
+		bucket = int(math.Log(us) / logHistogramGrowth)
+	}
+	h.Counts[bucket]++
+	h.Total++
+}
+
+// Merge adds the counts of other.
+func (h *Histogram) Merge(other *Histogram) {
+	for bucket, count := range other.Counts {
+		h.Counts[bucket] += count
+	}
+	h.Total += other.Total
+}
+
+// Percentile returns the duration below which the fraction p of the recorded
+// durations fall, using the same rank as the exact calculation on sorted
+// durations.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	rank := int(float64(h.Total) * p)
+	if rank >= h.Total {
+		rank = h.Total - 1
+	}
+
+	buckets := make([]int, 0, len(h.Counts))
+	for bucket := range h.Counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	seen := 0
+	for _, bucket := range buckets {
+		seen += h.Counts[bucket]
+		if seen > rank {
+			// Report the middle of the bucket.
+			us := math.Pow(histogramGrowth, float64(bucket)+0.5)
+			return time.Duration(us * float64(time.Microsecond))
+		}
+	}
+	return 0
+}
+
+// Aggregate is a fixed-size summary of request results. Load generators
+// send aggregates instead of every RequestResult, and aggregates from
+// several generators are merged into one report.
+type Aggregate struct {
+	TotalRequests    int
+	SuccessRequests  int
+	FailedRequests   int
+	TotalLatency     time.Duration
+	MinDuration      time.Duration
+	MaxDuration      time.Duration
+	Latency          *Histogram
+	StatusCodeCounts map[int]int
+	GRPCStatusCounts map[string]int
+	DNSRcodeCounts   map[string]int
+	URLCounts        map[string]int
+	ErrorClassCounts map[ErrorClass]int
+	Phases           map[string]*GroupAggregate
+	Operations       map[string]*GroupAggregate
+	TokenFetches     map[string]*GroupAggregate
+	Errors           []ErrorSummary
+	FirstErrorTime   time.Time
+	LastErrorTime    time.Time
+}
+
+// NewAggregate returns an empty Aggregate.
+func NewAggregate() *Aggregate {
+	return &Aggregate{
+		Latency:          NewHistogram(),
+		StatusCodeCounts: make(map[int]int),
+		GRPCStatusCounts: make(map[string]int),
+		DNSRcodeCounts:   make(map[string]int),
+		URLCounts:        make(map[string]int),
+		ErrorClassCounts: make(map[ErrorClass]int),
+		Phases:           make(map[string]*GroupAggregate),
+		Operations:       make(map[string]*GroupAggregate),
+		TokenFetches:     make(map[string]*GroupAggregate),
+	}
+}
+
+// Summarize aggregates the individual request results.
+func (r *Results) Summarize() *Aggregate {
+	a := NewAggregate()
+	stats := r.CalculateStatistics()
+
+	a.TotalRequests = stats.TotalRequests
+	a.SuccessRequests = stats.SuccessRequests
+	a.FailedRequests = stats.FailedRequests
+	a.MinDuration = stats.MinDuration
+	a.MaxDuration = stats.MaxDuration
+	a.StatusCodeCounts = stats.StatusCodeCounts
+	a.GRPCStatusCounts = stats.GRPCStatusCounts
+	a.DNSRcodeCounts = stats.DNSRcodeCounts
+	a.URLCounts = stats.URLCounts
+	a.ErrorClassCounts = stats.ErrorClassCounts
+	a.Phases = summarizeGroups(r.Requests, phaseOf)
+	a.Operations = summarizeGroups(r.Requests, operationOf)
+	a.TokenFetches = summarizeGroups(r.TokenFetches, urlOf)
+	a.Errors = groupErrors(r.Requests)
+	a.FirstErrorTime = stats.FirstErrorTime
+	a.LastErrorTime = stats.LastErrorTime
+	for _, req := range r.Requests {
+		a.Latency.Record(req.Duration)
+		a.TotalLatency += req.Duration
+	}
+	return a
+}
+
+// Merge adds the counts of other.
+func (a *Aggregate) Merge(other *Aggregate) {
+	if a.TokenFetches == nil {
+		a.TokenFetches = make(map[string]*GroupAggregate)
+	}
+	mergeGroups(a.TokenFetches, other.TokenFetches)
+	if other.TotalRequests == 0 {
+		return
+	}
+	if a.TotalRequests == 0 || other.MinDuration < a.MinDuration {
+		a.MinDuration = other.MinDuration
+	}
+	if other.MaxDuration > a.MaxDuration {
+		a.MaxDuration = other.MaxDuration
+	}
+	a.TotalRequests += other.TotalRequests
+	a.SuccessRequests += other.SuccessRequests
+	a.FailedRequests += other.FailedRequests
+	a.TotalLatency += other.TotalLatency
+	a.Latency.Merge(other.Latency)
+
+	for code, count := range other.StatusCodeCounts {
+		a.StatusCodeCounts[code] += count
+	}
+	for code, count := range other.GRPCStatusCounts {
+		a.GRPCStatusCounts[code] += count
+	}
+	for code, count := range other.DNSRcodeCounts {
+		a.DNSRcodeCounts[code] += count
+	}
+	for url, count := range other.URLCounts {
+		a.URLCounts[url] += count
+	}
+	for class, count := range other.ErrorClassCounts {
+		a.ErrorClassCounts[class] += count
+	}
+	mergeGroups(a.Phases, other.Phases)
+	mergeGroups(a.Operations, other.Operations)
+	a.Errors = mergeErrors(a.Errors, other.Errors)
+
+	if !other.FirstErrorTime.IsZero() && (a.FirstErrorTime.IsZero() || other.FirstErrorTime.Before(a.FirstErrorTime)) {
+		a.FirstErrorTime = other.FirstErrorTime
+	}
+	if other.LastErrorTime.After(a.LastErrorTime) {
+		a.LastErrorTime = other.LastErrorTime
+	}
+}
+
+// statistics derives the report statistics from the aggregate. Percentiles
+// are read from the latency histogram.
+func (a *Aggregate) statistics(totalDuration time.Duration) Statistics {
+	stats := Statistics{
+		TotalRequests:    a.TotalRequests,
+		SuccessRequests:  a.SuccessRequests,
+		FailedRequests:   a.FailedRequests,
+		StatusCodeCounts: a.StatusCodeCounts,
+		GRPCStatusCounts: a.GRPCStatusCounts,
+		DNSRcodeCounts:   a.DNSRcodeCounts,
+		URLCounts:        a.URLCounts,
+		ErrorClassCounts: a.ErrorClassCounts,
+		FirstErrorTime:   a.FirstErrorTime,
+		LastErrorTime:    a.LastErrorTime,
+		TokenFetches:     aggregateGroupStatistics(a.TokenFetches),
+	}
+	if a.TotalRequests == 0 {
+		return stats
+	}
+
+	stats.TotalDuration = totalDuration
+	stats.MinDuration = a.MinDuration
+	stats.MaxDuration = a.MaxDuration
+	stats.AvgDuration = a.TotalLatency / time.Duration(a.TotalRequests)
+	stats.MedianDuration = clampDuration(a.Latency.Percentile(0.5), a.MinDuration, a.MaxDuration)
+	stats.P95Duration = clampDuration(a.Latency.Percentile(0.95), a.MinDuration, a.MaxDuration)
+	stats.P99Duration = clampDuration(a.Latency.Percentile(0.99), a.MinDuration, a.MaxDuration)
+	stats.RequestsPerSec = float64(a.TotalRequests) / totalDuration.Seconds()
+	stats.Phases = aggregateGroupStatistics(a.Phases)
+	stats.Operations = aggregateGroupStatistics(a.Operations)
+	stats.TopErrors = topErrors(append([]ErrorSummary(nil), a.Errors...))
+	return stats
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	if d < lo {
+		return lo
+	}
+	if d > hi {
+		return hi
+	}
+	return d
+}
@@ -0,0 +1,35 @@
+package report
+
+import "strconv"
+
+// Protocols of the socket executors. ProtocolDNS marks a RequestResult
+// whose StatusCode is a DNS response code rather than an HTTP status code.
+const (
+	ProtocolDNS = "dns"
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// dnsRcodeNames are the names of the DNS response codes, indexed by code.
+var dnsRcodeNames = []string{
+	"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED",
+	"YXDOMAIN", "YXRRSET", "NXRRSET", "NOTAUTH", "NOTZONE",
+}
+
+// DNSRcodeName returns the name of a DNS response code, e.g. "NXDOMAIN" for
+// 3.
+func DNSRcodeName(code int) string {
+	if code >= 0 && code < len(dnsRcodeNames) {
+		return dnsRcodeNames[code]
+	}
+	return "RCODE_" + strconv.Itoa(code)
+}
+
+// ClassifyDNSRcode returns the error class and message for a DNS response
+// code, or an empty class for NOERROR.
+func ClassifyDNSRcode(code int) (ErrorClass, string) {
+	if code == 0 {
+		return "", ""
+	}
+	return ErrorClassDNSRcode, "DNS " + DNSRcodeName(code)
+}
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"time"
+)
+
+// Drift tracks how the target behaves over a long run, from the requests
+// between consecutive checkpoints. A latency or error rate that keeps
+// rising points at leaks and other gradual degradation that the statistics
+// of the whole run hide.
+type Drift struct {
+	Checkpoints []Checkpoint
+	// P99Slope and AvgSlope are the change of the latency per hour, fitted
+	// by least squares over the checkpoints that saw requests.
+	P99Slope time.Duration
+	AvgSlope time.Duration
+	// ErrorRateSlope is the change of the error rate in percentage points
+	// per hour.
+	ErrorRateSlope float64
+}
+
+// Checkpoint holds the statistics of the requests sent between the
+// previous checkpoint, or the start of the run, and this one.
+type Checkpoint struct {
+	StartTime      time.Time
+	EndTime        time.Time
+	Requests       int
+	FailedRequests int
+	// ErrorRate is the percentage of failed requests.
+	ErrorRate      float64
+	RequestsPerSec float64
+	AvgDuration    time.Duration
+	P99Duration    time.Duration
+}
+
+// AddCheckpoint appends the checkpoint described by the statistics of its
+// requests and fits the slopes again.
+func (d *Drift) AddCheckpoint(start, end time.Time, stats Statistics) Checkpoint {
+	c := Checkpoint{
+		StartTime:      start,
+		EndTime:        end,
+		Requests:       stats.TotalRequests,
+		FailedRequests: stats.FailedRequests,
+		RequestsPerSec: stats.RequestsPerSec,
+		AvgDuration:    stats.AvgDuration,
+		P99Duration:    stats.P99Duration,
+	}
+	if stats.TotalRequests > 0 {
+		c.ErrorRate = float64(stats.FailedRequests) / float64(stats.TotalRequests) * 100
+	}
+	d.Checkpoints = append(d.Checkpoints, c)
+
+	// Every checkpoint is placed at the middle of its interval, in hours
+	// since the start of the first one.
+	var hours, p99, avg, errorRate []float64
+	origin := d.Checkpoints[0].StartTime
+	for _, c := range d.Checkpoints {
+		if c.Requests == 0 {
+			continue
+		}
+		mid := c.StartTime.Add(c.EndTime.Sub(c.StartTime) / 2)
+		hours = append(hours, mid.Sub(origin).Hours())
+		p99 = append(p99, float64(c.P99Duration))
+		avg = append(avg, float64(c.AvgDuration))
+		errorRate = append(errorRate, c.ErrorRate)
+	}
+	d.P99Slope = time.Duration(slope(hours, p99))
+	d.AvgSlope = time.Duration(slope(hours, avg))
+	d.ErrorRateSlope = slope(hours, errorRate)
+	return c
+}
+
+// slope returns the slope of the least squares line through the points, 0
+// when there are fewer than two distinct x values.
+func slope(x, y []float64) float64 {
+	if len(x) < 2 {
+		return 0
+	}
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var cov, varX float64
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		varX += (x[i] - meanX) * (x[i] - meanX)
+	}
+	if varX == 0 {
+		return 0
+	}
+	return cov / varX
+}
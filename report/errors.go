@@ -0,0 +1,245 @@
+package report
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type ErrorClass string
+
+const (
+	ErrorClassDNS               ErrorClass = "dns"
+	ErrorClassConnectionRefused ErrorClass = "connection_refused"
+	ErrorClassConnectionReset   ErrorClass = "connection_reset"
+	ErrorClassTimeout           ErrorClass = "timeout"
+	ErrorClassTLS               ErrorClass = "tls"
+	ErrorClassHTTP4xx           ErrorClass = "http_4xx"
+	ErrorClassHTTP5xx           ErrorClass = "http_5xx"
+	ErrorClassGRPC              ErrorClass = "grpc_status"
+	ErrorClassDNSRcode          ErrorClass = "dns_rcode"
+	ErrorClassCheck             ErrorClass = "check_failure"
+	ErrorClassAuth              ErrorClass = "auth"
+	ErrorClassOther             ErrorClass = "other"
+)
+
+// Request phases used to qualify timeout errors.
+const (
+	PhaseDial   = "dial"
+	PhaseTLS    = "tls"
+	PhaseHeader = "header"
+	PhaseBody   = "body"
+)
+
+const (
+	maxTopErrors       = 10
+	maxErrorSampleURLs = 3
+)
+
+type ErrorSummary struct {
+	Class      ErrorClass
+	Message    string
+	Count      int
+	SampleURLs []string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+// ClassifyError normalizes a transport error into an error class and a
+// message that no longer carries per-request details such as the URL or
+// ephemeral source port. phase is the request phase the error surfaced in
+// and is only used to qualify timeouts.
+func ClassifyError(err error, phase string) (ErrorClass, string) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	message := normalizeErrorMessage(err.Error())
+
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return ErrorClassDNS, message
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassConnectionRefused, message
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorClassConnectionReset, message
+	case strings.Contains(message, "TLS handshake timeout"):
+		return ErrorClassTimeout, "timeout (" + PhaseTLS + "): " + message
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout, "timeout (" + timeoutPhase(err, message, phase) + "): " + message
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &certErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &invalidCertErr),
+		strings.HasPrefix(message, "tls: "):
+		return ErrorClassTLS, message
+	}
+
+	return ErrorClassOther, message
+}
+
+// ClassifyStatus returns the error class and message for an HTTP status code
+// that should be counted as a failure, or an empty class for success codes.
+func ClassifyStatus(statusCode int) (ErrorClass, string) {
+	message := fmt.Sprintf("HTTP %d %s", statusCode, http.StatusText(statusCode))
+	switch {
+	case statusCode >= 500:
+		return ErrorClassHTTP5xx, message
+	case statusCode >= 400:
+		return ErrorClassHTTP4xx, message
+	}
+	return "", ""
+}
+
+func timeoutPhase(err error, message, phase string) string {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return PhaseDial
+	}
+	if strings.Contains(message, "awaiting headers") {
+		return PhaseHeader
+	}
+	if phase != "" {
+		return phase
+	}
+	return PhaseHeader
+}
+
+var (
+	ephemeralAddrPattern = regexp.MustCompile(`[0-9a-fA-F.:\[\]]+:\d+->`)
+	lookupServerPattern  = regexp.MustCompile(`on [0-9a-fA-F.:\[\]]+:53`)
+)
+
+func normalizeErrorMessage(message string) string {
+	message = ephemeralAddrPattern.ReplaceAllString(message, "")
+	message = lookupServerPattern.ReplaceAllString(message, "on <resolver>")
+	return message
+}
+
+// summarizeErrors groups failed requests by class and normalized message and
+// returns the most frequent ones.
+func summarizeErrors(requests []RequestResult) []ErrorSummary {
+	return topErrors(groupErrors(requests))
+}
+
+// groupErrors groups failed requests by class and normalized message, most
+// frequent first.
+func groupErrors(requests []RequestResult) []ErrorSummary {
+	type key struct {
+		class   ErrorClass
+		message string
+	}
+	index := make(map[key]int)
+	summaries := make([]ErrorSummary, 0)
+
+	for _, req := range requests {
+		if req.Error == "" {
+			continue
+		}
+		class := req.ErrorClass
+		if class == "" {
+			class = ErrorClassOther
+		}
+		k := key{class: class, message: req.Error}
+		i, ok := index[k]
+		if !ok {
+			i = len(summaries)
+			index[k] = i
+			summaries = append(summaries, ErrorSummary{
+				Class:     class,
+				Message:   req.Error,
+				FirstSeen: req.Timestamp,
+				LastSeen:  req.Timestamp,
+			})
+		}
+
+		s := &summaries[i]
+		s.Count++
+		if req.Timestamp.Before(s.FirstSeen) {
+			s.FirstSeen = req.Timestamp
+		}
+		if req.Timestamp.After(s.LastSeen) {
+			s.LastSeen = req.Timestamp
+		}
+		if req.URL != "" && len(s.SampleURLs) < maxErrorSampleURLs && !containsString(s.SampleURLs, req.URL) {
+			s.SampleURLs = append(s.SampleURLs, req.URL)
+		}
+	}
+
+	sortErrors(summaries)
+	return summaries
+}
+
+// mergeErrors combines two error groupings, adding up the groups with the
+// same class and message.
+func mergeErrors(a, b []ErrorSummary) []ErrorSummary {
+	merged := append([]ErrorSummary(nil), a...)
+	for _, e := range b {
+		i := 0
+		for ; i < len(merged); i++ {
+			if merged[i].Class == e.Class && merged[i].Message == e.Message {
+				break
+			}
+		}
+		if i == len(merged) {
+			e.SampleURLs = append([]string(nil), e.SampleURLs...)
+			merged = append(merged, e)
+			continue
+		}
+
+		s := &merged[i]
+		s.Count += e.Count
+		if e.FirstSeen.Before(s.FirstSeen) {
+			s.FirstSeen = e.FirstSeen
+		}
+		if e.LastSeen.After(s.LastSeen) {
+			s.LastSeen = e.LastSeen
+		}
+		for _, url := range e.SampleURLs {
+			if len(s.SampleURLs) < maxErrorSampleURLs && !containsString(s.SampleURLs, url) {
+				s.SampleURLs = append(s.SampleURLs, url)
+			}
+		}
+	}
+	sortErrors(merged)
+	return merged
+}
+
+func sortErrors(summaries []ErrorSummary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Count > summaries[j].Count
+	})
+}
+
+// topErrors returns the most frequent of the sorted error groups.
+func topErrors(summaries []ErrorSummary) []ErrorSummary {
+	if len(summaries) > maxTopErrors {
+		summaries = summaries[:maxTopErrors]
+	}
+	return summaries
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
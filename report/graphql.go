@@ -0,0 +1,30 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolGraphQL marks a RequestResult from a graphql endpoint. Its
+// Operation names the GraphQL operation.
+const ProtocolGraphQL = "graphql"
+
+// ClassifyGraphQLResponse returns the error class and message for the body
+// of a successful HTTP response to a GraphQL request, or an empty class when
+// the response carries data without errors. GraphQL servers report
+// failures in the errors field with status 200, so they count as failed
+// checks.
+func ClassifyGraphQLResponse(body []byte) (ErrorClass, string) {
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ErrorClassCheck, fmt.Sprintf("invalid GraphQL response: %v", err)
+	}
+	if len(resp.Errors) == 0 {
+		return "", ""
+	}
+	return ErrorClassCheck, "GraphQL error: " + resp.Errors[0].Message
+}
@@ -0,0 +1,181 @@
+package report
+
+import (
+	"sort"
+	"time"
+)
+
+// GroupStatistics summarizes the results that share a name, such as the
+// results of one connection phase or of one GraphQL operation.
+type GroupStatistics struct {
+	Name            string
+	Count           int
+	SuccessRequests int
+	FailedRequests  int
+	MinDuration     time.Duration
+	AvgDuration     time.Duration
+	MedianDuration  time.Duration
+	P95Duration     time.Duration
+	P99Duration     time.Duration
+	MaxDuration     time.Duration
+	// Messages is the number of messages or events received by the
+	// results; divided by Count it gives the messages per connection of
+	// PhaseSession.
+	Messages int
+}
+
+// MessagesPerConnection returns the average number of messages received per
+// result of the group.
+func (g GroupStatistics) MessagesPerConnection() float64 {
+	if g.Count == 0 {
+		return 0
+	}
+	return float64(g.Messages) / float64(g.Count)
+}
+
+// GroupAggregate is the mergeable form of GroupStatistics.
+type GroupAggregate struct {
+	Count          int
+	FailedRequests int
+	TotalLatency   time.Duration
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+	Messages       int
+	Latency        *Histogram
+}
+
+// Group keys.
+func phaseOf(req RequestResult) string     { return req.Phase }
+func operationOf(req RequestResult) string { return req.Operation }
+func urlOf(req RequestResult) string       { return req.URL }
+
+// groupStatistics breaks the results down by the name key returns, ordered
+// by name. Results with an empty name are left out.
+func groupStatistics(requests []RequestResult, key func(RequestResult) string) []GroupStatistics {
+	durations := make(map[string][]time.Duration)
+	failed := make(map[string]int)
+	messages := make(map[string]int)
+	for _, req := range requests {
+		name := key(req)
+		if name == "" {
+			continue
+		}
+		durations[name] = append(durations[name], req.Duration)
+		messages[name] += req.Messages
+		if req.Error != "" {
+			failed[name]++
+		}
+	}
+
+	groups := make([]GroupStatistics, 0, len(durations))
+	for name, ds := range durations {
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+		}
+		groups = append(groups, GroupStatistics{
+			Name:            name,
+			Count:           len(ds),
+			SuccessRequests: len(ds) - failed[name],
+			FailedRequests:  failed[name],
+			MinDuration:     ds[0],
+			AvgDuration:     total / time.Duration(len(ds)),
+			MedianDuration:  ds[len(ds)/2],
+			P95Duration:     ds[int(float64(len(ds))*0.95)],
+			P99Duration:     ds[int(float64(len(ds))*0.99)],
+			MaxDuration:     ds[len(ds)-1],
+			Messages:        messages[name],
+		})
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// summarizeGroups aggregates the results by the name key returns. Results
+// with an empty name are left out.
+func summarizeGroups(requests []RequestResult, key func(RequestResult) string) map[string]*GroupAggregate {
+	groups := make(map[string]*GroupAggregate)
+	for _, req := range requests {
+		name := key(req)
+		if name == "" {
+			continue
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &GroupAggregate{Latency: NewHistogram(), MinDuration: req.Duration}
+			groups[name] = g
+		}
+		g.Count++
+		if req.Error != "" {
+			g.FailedRequests++
+		}
+		g.TotalLatency += req.Duration
+		g.Messages += req.Messages
+		g.MinDuration = min(g.MinDuration, req.Duration)
+		g.MaxDuration = max(g.MaxDuration, req.Duration)
+		g.Latency.Record(req.Duration)
+	}
+	return groups
+}
+
+// Merge adds the counts of other.
+func (g *GroupAggregate) Merge(other *GroupAggregate) {
+	if other.Count == 0 {
+		return
+	}
+	if g.Count == 0 || other.MinDuration < g.MinDuration {
+		g.MinDuration = other.MinDuration
+	}
+	g.MaxDuration = max(g.MaxDuration, other.MaxDuration)
+	g.Count += other.Count
+	g.FailedRequests += other.FailedRequests
+	g.TotalLatency += other.TotalLatency
+	g.Messages += other.Messages
+	g.Latency.Merge(other.Latency)
+}
+
+func (g *GroupAggregate) statistics(name string) GroupStatistics {
+	stats := GroupStatistics{
+		Name:            name,
+		Count:           g.Count,
+		SuccessRequests: g.Count - g.FailedRequests,
+		FailedRequests:  g.FailedRequests,
+		Messages:        g.Messages,
+	}
+	if g.Count == 0 {
+		return stats
+	}
+	stats.MinDuration = g.MinDuration
+	stats.MaxDuration = g.MaxDuration
+	stats.AvgDuration = g.TotalLatency / time.Duration(g.Count)
+	stats.MedianDuration = clampDuration(g.Latency.Percentile(0.5), g.MinDuration, g.MaxDuration)
+	stats.P95Duration = clampDuration(g.Latency.Percentile(0.95), g.MinDuration, g.MaxDuration)
+	stats.P99Duration = clampDuration(g.Latency.Percentile(0.99), g.MinDuration, g.MaxDuration)
+	return stats
+}
+
+// mergeGroups adds the groups of src to dst.
+func mergeGroups(dst, src map[string]*GroupAggregate) {
+	for name, g := range src {
+		if _, ok := dst[name]; !ok {
+			dst[name] = &GroupAggregate{Latency: NewHistogram()}
+		}
+		dst[name].Merge(g)
+	}
+}
+
+// aggregateGroupStatistics derives the statistics of every group, ordered by
+// name.
+func aggregateGroupStatistics(groups map[string]*GroupAggregate) []GroupStatistics {
+	stats := make([]GroupStatistics, 0, len(groups))
+	for name, g := range groups {
+		stats = append(stats, g.statistics(name))
+	}
+	sortGroups(stats)
+	return stats
+}
+
+func sortGroups(groups []GroupStatistics) {
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+}
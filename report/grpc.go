@@ -0,0 +1,41 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ProtocolGRPC marks a RequestResult whose StatusCode is a gRPC status code
+// rather than an HTTP status code.
+const ProtocolGRPC = "grpc"
+
+// grpcCodeNames are the canonical names of the gRPC status codes, indexed by
+// code.
+var grpcCodeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// GRPCCodeName returns the canonical name of a gRPC status code, e.g.
+// "UNAVAILABLE" for 14.
+func GRPCCodeName(code int) string {
+	if code >= 0 && code < len(grpcCodeNames) {
+		return grpcCodeNames[code]
+	}
+	return "CODE_" + strconv.Itoa(code)
+}
+
+// ClassifyGRPCStatus returns the error class and message for a gRPC status,
+// or an empty class for OK.
+func ClassifyGRPCStatus(code int, message string) (ErrorClass, string) {
+	if code == 0 {
+		return "", ""
+	}
+	summary := fmt.Sprintf("gRPC %s: %s", GRPCCodeName(code), normalizeErrorMessage(message))
+	if GRPCCodeName(code) == "DEADLINE_EXCEEDED" {
+		return ErrorClassTimeout, summary
+	}
+	return ErrorClassGRPC, summary
+}
@@ -0,0 +1,564 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Load Test Report</title>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
+            max-width: 1200px;
+            margin: 0 auto;
+            padding: 20px;
+            background-color: #f5f5f5;
+        }
+        .header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 30px;
+            border-radius: 10px;
+            margin-bottom: 20px;
+        }
+        .header h1 {
+            margin: 0 0 10px 0;
+        }
+        .header p {
+            margin: 5px 0;
+            opacity: 0.9;
+        }
+        .section {
+            background: white;
+            padding: 20px;
+            margin-bottom: 20px;
+            border-radius: 10px;
+            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
+        }
+        .section h2 {
+            margin-top: 0;
+            color: #333;
+            border-bottom: 2px solid #667eea;
+            padding-bottom: 10px;
+        }
+        .stats-grid {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(200px, 1fr));
+            gap: 15px;
+            margin-top: 20px;
+        }
+        .stat-card {
+            background: #f8f9fa;
+            padding: 15px;
+            border-radius: 8px;
+            border-left: 4px solid #667eea;
+        }
+        .stat-label {
+            font-size: 12px;
+            color: #666;
+            text-transform: uppercase;
+            letter-spacing: 0.5px;
+        }
+        .stat-value {
+            font-size: 24px;
+            font-weight: bold;
+            color: #333;
+            margin-top: 5px;
+        }
+        .status-table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-top: 15px;
+        }
+        .status-table th,
+        .status-table td {
+            padding: 12px;
+            text-align: left;
+            border-bottom: 1px solid #e0e0e0;
+        }
+        .status-table th {
+            background-color: #f8f9fa;
+            font-weight: 600;
+            color: #333;
+        }
+        .status-table tr:hover {
+            background-color: #f8f9fa;
+        }
+        .success {
+            color: #28a745;
+        }
+        .error {
+            color: #dc3545;
+        }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>Load Test Report</h1>
+        <p><strong>Target Endpoints:</strong></p>
+        <ul style="margin: 5px 0; padding-left: 20px;">
+        {{range .URLs}}
+            <li>{{.}}</li>
+        {{end}}
+        </ul>
+        <p><strong>Test Duration:</strong> {{.Stats.TotalDuration}}</p>
+        <p><strong>Start Time:</strong> {{.StartTime.Format "2006-01-02 15:04:05"}}</p>
+        <p><strong>End Time:</strong> {{.EndTime.Format "2006-01-02 15:04:05"}}</p>
+        {{if .StopReason}}<p><strong>Stopped:</strong> {{.StopReason}}</p>{{end}}
+    </div>
+
+    <div class="section">
+        <h2>Configuration</h2>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">Target RPS</div>
+                <div class="stat-value">{{.RPS}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Concurrency</div>
+                <div class="stat-value">{{.Concurrency}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Duration</div>
+                <div class="stat-value">{{.Duration}}s</div>
+            </div>
+            {{if .Seed}}
+            <div class="stat-card">
+                <div class="stat-label">Seed</div>
+                <div class="stat-value">{{.Seed}}</div>
+            </div>
+            {{end}}
+        </div>
+    </div>
+
+    {{with .Search}}
+    <div class="section">
+        <h2>Capacity Search</h2>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">Highest Passing RPS</div>
+                <div class="stat-value {{if .MaxPassingRPS}}success{{else}}error{{end}}">{{if .MaxPassingRPS}}{{.MaxPassingRPS}}{{else}}none{{end}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Strategy</div>
+                <div class="stat-value">{{.Strategy}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Steps</div>
+                <div class="stat-value">{{len .Steps}}</div>
+            </div>
+        </div>
+        <div style="margin-top: 20px;">{{searchChart .}}</div>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Target RPS</th>
+                    <th>Achieved RPS</th>
+                    <th>Requests</th>
+                    <th>99th Percentile</th>
+                    <th>Error Rate</th>
+                    <th>Result</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range .Steps}}
+                <tr>
+                    <td>{{.TargetRPS}}</td>
+                    <td>{{printf "%.2f" .AchievedRPS}}</td>
+                    <td>{{.Requests}}</td>
+                    <td>{{.P99Duration}}</td>
+                    <td>{{printf "%.2f" .ErrorRate}}%</td>
+                    <td>{{if .Passed}}<span class="success">pass</span>{{else}}<span class="error">fail</span>: {{range $i, $v := .Violations}}{{if $i}}; {{end}}{{$v}}{{end}}{{end}}</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+    {{end}}
+
+    <div class="section">
+        <h2>Summary</h2>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">Total Requests</div>
+                <div class="stat-value">{{.Stats.TotalRequests}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Success</div>
+                <div class="stat-value success">{{.Stats.SuccessRequests}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Failed</div>
+                <div class="stat-value error">{{.Stats.FailedRequests}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Actual RPS</div>
+                <div class="stat-value">{{printf "%.2f" .Stats.RequestsPerSec}}</div>
+            </div>
+        </div>
+    </div>
+
+    <div class="section">
+        <h2>Response Time Statistics</h2>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">Min</div>
+                <div class="stat-value">{{.Stats.MinDuration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Average</div>
+                <div class="stat-value">{{.Stats.AvgDuration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Median</div>
+                <div class="stat-value">{{.Stats.MedianDuration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">95th Percentile</div>
+                <div class="stat-value">{{.Stats.P95Duration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">99th Percentile</div>
+                <div class="stat-value">{{.Stats.P99Duration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Max</div>
+                <div class="stat-value">{{.Stats.MaxDuration}}</div>
+            </div>
+        </div>
+    </div>
+
+    {{with .WarmupStats}}
+    <div class="section">
+        <h2>Warm-up</h2>
+        <p>Requests sent at {{$.Warmup.RPS}} RPS from {{$.Warmup.StartTime.Format "15:04:05"}} to {{$.Warmup.EndTime.Format "15:04:05"}} before measurement. They are {{if $.Warmup.Included}}included in{{else}}left out of{{end}} the statistics above.</p>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">Total Requests</div>
+                <div class="stat-value">{{.TotalRequests}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Failed</div>
+                <div class="stat-value error">{{.FailedRequests}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Actual RPS</div>
+                <div class="stat-value">{{printf "%.2f" .RequestsPerSec}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Min</div>
+                <div class="stat-value">{{.MinDuration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Average</div>
+                <div class="stat-value">{{.AvgDuration}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">99th Percentile</div>
+                <div class="stat-value">{{.P99Duration}}</div>
+            </div>
+        </div>
+    </div>
+    {{end}}
+
+    {{with .Drift}}
+    <div class="section">
+        <h2>Drift</h2>
+        <p>Trends across the checkpoints, fitted by least squares. A steadily rising latency or error rate points at gradual degradation such as a leak.</p>
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-label">99th Percentile Trend</div>
+                <div class="stat-value">{{perHour .P99Slope}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Average Trend</div>
+                <div class="stat-value">{{perHour .AvgSlope}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Error Rate Trend</div>
+                <div class="stat-value">{{printf "%+.3f" .ErrorRateSlope}} pp/h</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Checkpoints</div>
+                <div class="stat-value">{{len .Checkpoints}}</div>
+            </div>
+        </div>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Interval</th>
+                    <th>Requests</th>
+                    <th>Actual RPS</th>
+                    <th>Average</th>
+                    <th>99th Percentile</th>
+                    <th>Error Rate</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range .Checkpoints}}
+                <tr>
+                    <td>{{.StartTime.Format "15:04:05"}} - {{.EndTime.Format "15:04:05"}}</td>
+                    <td>{{.Requests}}</td>
+                    <td>{{printf "%.2f" .RequestsPerSec}}</td>
+                    <td>{{.AvgDuration}}</td>
+                    <td>{{.P99Duration}}</td>
+                    <td>{{printf "%.2f" .ErrorRate}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+    {{end}}
+
+    {{if .Stats.Phases}}
+    <div class="section">
+        <h2>Connection Phases</h2>
+        {{template "groups" .Stats.Phases}}
+    </div>
+    {{end}}
+
+    {{if .Stats.Operations}}
+    <div class="section">
+        <h2>GraphQL Operations</h2>
+        {{template "groups" .Stats.Operations}}
+    </div>
+    {{end}}
+
+    {{if .Stats.TokenFetches}}
+    <div class="section">
+        <h2>Token Fetches</h2>
+        <p>Requests for authentication tokens, not counted in the results above.</p>
+        {{template "groups" .Stats.TokenFetches}}
+    </div>
+    {{end}}
+
+    <div class="section">
+        <h2>Status Code Distribution</h2>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Status Code</th>
+                    <th>Count</th>
+                    <th>Percentage</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $code, $count := .Stats.StatusCodeCounts}}
+                <tr>
+                    <td>{{$code}}</td>
+                    <td>{{$count}}</td>
+                    <td>{{printf "%.2f" (percentage $count $.Stats.TotalRequests)}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+
+    {{if .Stats.GRPCStatusCounts}}
+    <div class="section">
+        <h2>gRPC Status Code Distribution</h2>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Status Code</th>
+                    <th>Count</th>
+                    <th>Percentage</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $code, $count := .Stats.GRPCStatusCounts}}
+                <tr>
+                    <td>{{$code}}</td>
+                    <td>{{$count}}</td>
+                    <td>{{printf "%.2f" (percentage $count $.Stats.TotalRequests)}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+    {{end}}
+
+    {{if .Stats.DNSRcodeCounts}}
+    <div class="section">
+        <h2>DNS Response Code Distribution</h2>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Response Code</th>
+                    <th>Count</th>
+                    <th>Percentage</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $code, $count := .Stats.DNSRcodeCounts}}
+                <tr>
+                    <td>{{$code}}</td>
+                    <td>{{$count}}</td>
+                    <td>{{printf "%.2f" (percentage $count $.Stats.TotalRequests)}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+    {{end}}
+
+    <div class="section">
+        <h2>Endpoint Distribution</h2>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Endpoint</th>
+                    <th>Count</th>
+                    <th>Percentage</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $url, $count := .Stats.URLCounts}}
+                <tr>
+                    <td>{{$url}}</td>
+                    <td>{{$count}}</td>
+                    <td>{{printf "%.2f" (percentage $count $.Stats.TotalRequests)}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+
+    {{if .Stats.FailedRequests}}
+    <div class="section">
+        <h2>Errors</h2>
+        <p><strong>First Error:</strong> {{.Stats.FirstErrorTime.Format "2006-01-02 15:04:05"}}</p>
+        <p><strong>Last Error:</strong> {{.Stats.LastErrorTime.Format "2006-01-02 15:04:05"}}</p>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Class</th>
+                    <th>Count</th>
+                    <th>Percentage</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $class, $count := .Stats.ErrorClassCounts}}
+                <tr>
+                    <td class="error">{{$class}}</td>
+                    <td>{{$count}}</td>
+                    <td>{{printf "%.2f" (percentage $count $.Stats.TotalRequests)}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+
+        <h3>Top Errors</h3>
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Class</th>
+                    <th>Message</th>
+                    <th>Count</th>
+                    <th>Sample URLs</th>
+                    <th>First Seen</th>
+                    <th>Last Seen</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range .Stats.TopErrors}}
+                <tr>
+                    <td class="error">{{.Class}}</td>
+                    <td>{{.Message}}</td>
+                    <td>{{.Count}}</td>
+                    <td>{{range .SampleURLs}}{{.}}<br>{{end}}</td>
+                    <td>{{.FirstSeen.Format "15:04:05"}}</td>
+                    <td>{{.LastSeen.Format "15:04:05"}}</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+    </div>
+    {{end}}
+</body>
+</html>
+{{define "groups"}}
+        <table class="status-table">
+            <thead>
+                <tr>
+                    <th>Name</th>
+                    <th>Count</th>
+                    <th>Failed</th>
+                    <th>Min</th>
+                    <th>Average</th>
+                    <th>Median</th>
+                    <th>95th Percentile</th>
+                    <th>99th Percentile</th>
+                    <th>Max</th>
+                    <th>Messages per Connection</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range .}}
+                <tr>
+                    <td>{{.Name}}</td>
+                    <td>{{.Count}}</td>
+                    <td>{{.FailedRequests}}</td>
+                    <td>{{.MinDuration}}</td>
+                    <td>{{.AvgDuration}}</td>
+                    <td>{{.MedianDuration}}</td>
+                    <td>{{.P95Duration}}</td>
+                    <td>{{.P99Duration}}</td>
+                    <td>{{.MaxDuration}}</td>
+                    <td>{{if .Messages}}{{printf "%.1f" .MessagesPerConnection}}{{else}}-{{end}}</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+{{end}}
+`
+
+func GenerateHTML(w io.Writer, results *Results) error {
+	stats := results.CalculateStatistics()
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"percentage": func(count, total int) float64 {
+			if total == 0 {
+				return 0
+			}
+			return float64(count) / float64(total) * 100
+		},
+		"searchChart": searchChart,
+		"perHour": func(d time.Duration) string {
+			d = d.Round(time.Microsecond)
+			if d >= 0 {
+				return "+" + d.String() + "/h"
+			}
+			return d.String() + "/h"
+		},
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	data := struct {
+		*Results
+		Stats       Statistics
+		WarmupStats *Statistics
+	}{
+		Results: results,
+		Stats:   stats,
+	}
+	if results.Warmup != nil {
+		warmup := results.Warmup.Statistics()
+		data.WarmupStats = &warmup
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type JSONReport struct {
+	URLs         []string            `json:"urls"`
+	RPS          int                 `json:"rps"`
+	Concurrency  int                 `json:"concurrency"`
+	Duration     int                 `json:"duration"`
+	StartTime    string              `json:"start_time"`
+	EndTime      string              `json:"end_time"`
+	Stop         *JSONStop           `json:"stop,omitempty"`
+	Seed         int64               `json:"seed,omitempty"`
+	Statistics   JSONStatistics      `json:"statistics"`
+	StatusCodes  map[int]int         `json:"status_codes"`
+	GRPCCodes    map[string]int      `json:"grpc_status_codes,omitempty"`
+	DNSRcodes    map[string]int      `json:"dns_rcodes,omitempty"`
+	URLCounts    map[string]int      `json:"url_counts"`
+	Phases       []JSONGroup         `json:"phases,omitempty"`
+	Operations   []JSONGroup         `json:"operations,omitempty"`
+	TokenFetches []JSONGroup         `json:"token_fetches,omitempty"`
+	Errors       JSONErrors          `json:"errors"`
+	Search       *JSONSearch         `json:"search,omitempty"`
+	Drift        *JSONDrift          `json:"drift,omitempty"`
+	Warmup       *JSONWarmup         `json:"warmup,omitempty"`
+	Requests     []JSONRequestResult `json:"requests,omitempty"`
+}
+
+type JSONStop struct {
+	Condition string `json:"condition"`
+	Reason    string `json:"reason"`
+}
+
+type JSONStatistics struct {
+	TotalRequests    int     `json:"total_requests"`
+	SuccessRequests  int     `json:"success_requests"`
+	FailedRequests   int     `json:"failed_requests"`
+	TotalDurationMs  int64   `json:"total_duration_ms"`
+	MinDurationMs    int64   `json:"min_duration_ms"`
+	MaxDurationMs    int64   `json:"max_duration_ms"`
+	AvgDurationMs    int64   `json:"avg_duration_ms"`
+	MedianDurationMs int64   `json:"median_duration_ms"`
+	P95DurationMs    int64   `json:"p95_duration_ms"`
+	P99DurationMs    int64   `json:"p99_duration_ms"`
+	RequestsPerSec   float64 `json:"requests_per_sec"`
+}
+
+type JSONGroup struct {
+	Name             string `json:"name"`
+	Count            int    `json:"count"`
+	SuccessRequests  int    `json:"success_requests"`
+	FailedRequests   int    `json:"failed_requests"`
+	MinDurationMs    int64  `json:"min_duration_ms"`
+	MaxDurationMs    int64  `json:"max_duration_ms"`
+	AvgDurationMs    int64  `json:"avg_duration_ms"`
+	MedianDurationMs int64  `json:"median_duration_ms"`
+	P95DurationMs    int64  `json:"p95_duration_ms"`
+	P99DurationMs    int64  `json:"p99_duration_ms"`
+	Messages         int    `json:"messages,omitempty"`
+}
+
+type JSONSearch struct {
+	Strategy      string           `json:"strategy"`
+	MaxPassingRPS int              `json:"max_passing_rps"`
+	Steps         []JSONSearchStep `json:"steps"`
+}
+
+type JSONSearchStep struct {
+	TargetRPS     int      `json:"target_rps"`
+	AchievedRPS   float64  `json:"achieved_rps"`
+	Requests      int      `json:"requests"`
+	P99DurationMs int64    `json:"p99_duration_ms"`
+	ErrorRate     float64  `json:"error_rate"`
+	Passed        bool     `json:"passed"`
+	Violations    []string `json:"violations,omitempty"`
+}
+
+type JSONDrift struct {
+	P99SlopeMsPerHour float64          `json:"p99_slope_ms_per_hour"`
+	AvgSlopeMsPerHour float64          `json:"avg_slope_ms_per_hour"`
+	ErrorRateSlope    float64          `json:"error_rate_slope_per_hour"`
+	Checkpoints       []JSONCheckpoint `json:"checkpoints"`
+}
+
+type JSONCheckpoint struct {
+	StartTime      string  `json:"start_time"`
+	EndTime        string  `json:"end_time"`
+	Requests       int     `json:"requests"`
+	FailedRequests int     `json:"failed_requests"`
+	ErrorRate      float64 `json:"error_rate"`
+	RequestsPerSec float64 `json:"requests_per_sec"`
+	AvgDurationMs  int64   `json:"avg_duration_ms"`
+	P99DurationMs  int64   `json:"p99_duration_ms"`
+}
+
+type JSONWarmup struct {
+	RPS        int            `json:"rps"`
+	StartTime  string         `json:"start_time"`
+	EndTime    string         `json:"end_time"`
+	Included   bool           `json:"included"`
+	Statistics JSONStatistics `json:"statistics"`
+}
+
+type JSONRequestResult struct {
+	Timestamp  string `json:"timestamp"`
+	DurationMs int64  `json:"duration_ms"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+	Phase      string `json:"phase,omitempty"`
+	Messages   int    `json:"messages,omitempty"`
+	Operation  string `json:"operation,omitempty"`
+	Warmup     bool   `json:"warmup,omitempty"`
+}
+
+type JSONErrors struct {
+	Classes   map[ErrorClass]int `json:"classes"`
+	Top       []JSONErrorSummary `json:"top"`
+	FirstSeen string             `json:"first_seen,omitempty"`
+	LastSeen  string             `json:"last_seen,omitempty"`
+}
+
+type JSONErrorSummary struct {
+	Class      ErrorClass `json:"class"`
+	Message    string     `json:"message"`
+	Count      int        `json:"count"`
+	SampleURLs []string   `json:"sample_urls,omitempty"`
+	FirstSeen  string     `json:"first_seen"`
+	LastSeen   string     `json:"last_seen"`
+}
+
+func GenerateJSON(w io.Writer, results *Results) error {
+	stats := results.CalculateStatistics()
+
+	report := JSONReport{
+		URLs:        results.URLs,
+		RPS:         results.RPS,
+		Concurrency: results.Concurrency,
+		Duration:    results.Duration,
+		StartTime:   results.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+		EndTime:     results.EndTime.Format("2006-01-02T15:04:05Z07:00"),
+		Seed:        results.Seed,
+		Statistics:  jsonStatistics(stats),
+		StatusCodes: stats.StatusCodeCounts,
+		GRPCCodes:   stats.GRPCStatusCounts,
+		DNSRcodes:   stats.DNSRcodeCounts,
+		URLCounts:   stats.URLCounts,
+		Errors: JSONErrors{
+			Classes: stats.ErrorClassCounts,
+			Top:     make([]JSONErrorSummary, 0, len(stats.TopErrors)),
+		},
+		Requests: make([]JSONRequestResult, 0, len(results.Requests)),
+	}
+
+	if !stats.FirstErrorTime.IsZero() {
+		report.Errors.FirstSeen = stats.FirstErrorTime.Format("2006-01-02T15:04:05Z07:00")
+		report.Errors.LastSeen = stats.LastErrorTime.Format("2006-01-02T15:04:05Z07:00")
+	}
+	report.Phases = jsonGroups(stats.Phases)
+	report.Operations = jsonGroups(stats.Operations)
+	report.TokenFetches = jsonGroups(stats.TokenFetches)
+	if s := results.Search; s != nil {
+		report.Search = &JSONSearch{Strategy: s.Strategy, MaxPassingRPS: s.MaxPassingRPS, Steps: make([]JSONSearchStep, 0, len(s.Steps))}
+		for _, step := range s.Steps {
+			report.Search.Steps = append(report.Search.Steps, JSONSearchStep{
+				TargetRPS:     step.TargetRPS,
+				AchievedRPS:   step.AchievedRPS,
+				Requests:      step.Requests,
+				P99DurationMs: step.P99Duration.Milliseconds(),
+				ErrorRate:     step.ErrorRate,
+				Passed:        step.Passed,
+				Violations:    step.Violations,
+			})
+		}
+	}
+	if results.StopCondition != "" {
+		report.Stop = &JSONStop{Condition: results.StopCondition, Reason: results.StopReason}
+	}
+	if d := results.Drift; d != nil {
+		report.Drift = &JSONDrift{
+			P99SlopeMsPerHour: milliseconds(d.P99Slope),
+			AvgSlopeMsPerHour: milliseconds(d.AvgSlope),
+			ErrorRateSlope:    d.ErrorRateSlope,
+			Checkpoints:       make([]JSONCheckpoint, 0, len(d.Checkpoints)),
+		}
+		for _, c := range d.Checkpoints {
+			report.Drift.Checkpoints = append(report.Drift.Checkpoints, JSONCheckpoint{
+				StartTime:      c.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+				EndTime:        c.EndTime.Format("2006-01-02T15:04:05Z07:00"),
+				Requests:       c.Requests,
+				FailedRequests: c.FailedRequests,
+				ErrorRate:      c.ErrorRate,
+				RequestsPerSec: c.RequestsPerSec,
+				AvgDurationMs:  c.AvgDuration.Milliseconds(),
+				P99DurationMs:  c.P99Duration.Milliseconds(),
+			})
+		}
+	}
+	if wu := results.Warmup; wu != nil {
+		report.Warmup = &JSONWarmup{
+			RPS:        wu.RPS,
+			StartTime:  wu.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+			EndTime:    wu.EndTime.Format("2006-01-02T15:04:05Z07:00"),
+			Included:   wu.Included,
+			Statistics: jsonStatistics(wu.Statistics()),
+		}
+	}
+	for _, e := range stats.TopErrors {
+		report.Errors.Top = append(report.Errors.Top, JSONErrorSummary{
+			Class:      e.Class,
+			Message:    e.Message,
+			Count:      e.Count,
+			SampleURLs: e.SampleURLs,
+			FirstSeen:  e.FirstSeen.Format("2006-01-02T15:04:05Z07:00"),
+			LastSeen:   e.LastSeen.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	// Include individual request results, the warm-up requests first
+	if results.Warmup != nil {
+		for _, req := range results.Warmup.Requests {
+			report.Requests = append(report.Requests, jsonRequestResult(req))
+		}
+	}
+	for _, req := range results.Requests {
+		report.Requests = append(report.Requests, jsonRequestResult(req))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateNDJSON writes the individual request results as newline
+// delimited JSON, one request per line in the format of the requests of a
+// JSON report.
+func GenerateNDJSON(w io.Writer, requests []RequestResult) error {
+	encoder := json.NewEncoder(w)
+	for _, req := range requests {
+		if err := encoder.Encode(jsonRequestResult(req)); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+func jsonRequestResult(req RequestResult) JSONRequestResult {
+	return JSONRequestResult{
+		Timestamp:  req.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		DurationMs: req.Duration.Milliseconds(),
+		StatusCode: req.StatusCode,
+		Error:      req.Error,
+		ErrorClass: string(req.ErrorClass),
+		URL:        req.URL,
+		Protocol:   req.Protocol,
+		Phase:      req.Phase,
+		Messages:   req.Messages,
+		Operation:  req.Operation,
+		Warmup:     req.Warmup,
+	}
+}
+
+func jsonStatistics(stats Statistics) JSONStatistics {
+	return JSONStatistics{
+		TotalRequests:    stats.TotalRequests,
+		SuccessRequests:  stats.SuccessRequests,
+		FailedRequests:   stats.FailedRequests,
+		TotalDurationMs:  stats.TotalDuration.Milliseconds(),
+		MinDurationMs:    stats.MinDuration.Milliseconds(),
+		MaxDurationMs:    stats.MaxDuration.Milliseconds(),
+		AvgDurationMs:    stats.AvgDuration.Milliseconds(),
+		MedianDurationMs: stats.MedianDuration.Milliseconds(),
+		P95DurationMs:    stats.P95Duration.Milliseconds(),
+		P99DurationMs:    stats.P99Duration.Milliseconds(),
+		RequestsPerSec:   stats.RequestsPerSec,
+	}
+}
+
+// milliseconds returns d in fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func jsonGroups(groups []GroupStatistics) []JSONGroup {
+	var out []JSONGroup
+	for _, g := range groups {
+		out = append(out, JSONGroup{
+			Name:             g.Name,
+			Count:            g.Count,
+			SuccessRequests:  g.SuccessRequests,
+			FailedRequests:   g.FailedRequests,
+			MinDurationMs:    g.MinDuration.Milliseconds(),
+			MaxDurationMs:    g.MaxDuration.Milliseconds(),
+			AvgDurationMs:    g.AvgDuration.Milliseconds(),
+			MedianDurationMs: g.MedianDuration.Milliseconds(),
+			P95DurationMs:    g.P95Duration.Milliseconds(),
+			P99DurationMs:    g.P99Duration.Milliseconds(),
+			Messages:         g.Messages,
+		})
+	}
+	return out
+}
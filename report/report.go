@@ -0,0 +1,234 @@
+// Package report computes statistics from load test results and renders
+// them as HTML or JSON reports.
+package report
+
+import (
+	"sort"
+	"time"
+)
+
+type Results struct {
+	URLs        []string
+	RPS         int
+	Concurrency int
+	Duration    int
+	StartTime   time.Time
+	EndTime     time.Time
+	Requests    []RequestResult
+	// TokenFetches are the requests for authentication tokens. They are
+	// kept apart from Requests so that they do not count towards the load
+	// results.
+	TokenFetches []RequestResult
+	// Search holds the steps of a capacity search; Requests then holds
+	// the requests of every step. It is nil in other modes.
+	Search *SearchResult
+	// Drift holds the checkpoints of a run with checkpoints enabled; it is
+	// nil otherwise.
+	Drift *Drift
+	// StopCondition is why a load mode run ended, one of the Stop
+	// constants, and StopReason describes it. Both are empty in other
+	// modes.
+	StopCondition string
+	StopReason    string
+	// Seed is the seed of the random choices of a load or search mode run;
+	// running again with it sends the same requests in the same order.
+	Seed int64
+	// Warmup holds the requests sent before measurement; it is nil when
+	// the run had no warm-up.
+	Warmup *Warmup
+	// Aggregate replaces Requests when the results were collected from
+	// several load generators as aggregates.
+	Aggregate *Aggregate
+}
+
+// Conditions that end a load mode run.
+const (
+	StopDuration          = "duration"
+	StopMaxRequests       = "max_requests"
+	StopIterations        = "iterations"
+	StopConsecutiveErrors = "consecutive_errors"
+	StopErrorRate         = "error_rate"
+	StopThreshold         = "threshold"
+	StopInterrupted       = "interrupted"
+)
+
+type RequestResult struct {
+	Timestamp time.Time
+	Duration  time.Duration
+	// StatusCode is the HTTP status code, the gRPC status code when
+	// Protocol is ProtocolGRPC or the DNS response code when Protocol is
+	// ProtocolDNS.
+	StatusCode int
+	Error      string
+	ErrorClass ErrorClass
+	URL        string
+	// Protocol is empty for HTTP requests.
+	Protocol string
+	// Phase is the connection phase the result measures, for protocols
+	// that report several results per connection such as WebSocket. It is
+	// empty for plain requests.
+	Phase string
+	// Messages is the number of messages or events received on the
+	// connection. It is set on PhaseSession results.
+	Messages int
+	// Operation is the GraphQL operation name, for results of GraphQL
+	// endpoints.
+	Operation string
+	// Warmup marks requests sent during the warm-up before measurement.
+	Warmup bool
+}
+
+type Statistics struct {
+	TotalRequests    int
+	SuccessRequests  int
+	FailedRequests   int
+	TotalDuration    time.Duration
+	MinDuration      time.Duration
+	MaxDuration      time.Duration
+	AvgDuration      time.Duration
+	MedianDuration   time.Duration
+	P95Duration      time.Duration
+	P99Duration      time.Duration
+	RequestsPerSec   float64
+	StatusCodeCounts map[int]int
+	GRPCStatusCounts map[string]int
+	DNSRcodeCounts   map[string]int
+	URLCounts        map[string]int
+	ErrorClassCounts map[ErrorClass]int
+	// Phases breaks results down by connection phase; it is empty unless
+	// a connection-oriented endpoint was tested.
+	Phases []GroupStatistics
+	// Operations breaks results down by GraphQL operation name; it is
+	// empty unless a GraphQL endpoint was tested.
+	Operations []GroupStatistics
+	// TokenFetches breaks the token requests down by token URL; it is
+	// empty unless OAuth2 authentication was used.
+	TokenFetches   []GroupStatistics
+	TopErrors      []ErrorSummary
+	FirstErrorTime time.Time
+	LastErrorTime  time.Time
+}
+
+// CalculateStatistics returns the statistics of the measured requests.
+// Warm-up requests are left out unless the warm-up is included.
+func (r *Results) CalculateStatistics() Statistics {
+	if r.Warmup != nil && r.Warmup.Included {
+		return r.withWarmup().CalculateStatistics()
+	}
+	if r.Aggregate != nil {
+		return r.Aggregate.statistics(r.EndTime.Sub(r.StartTime))
+	}
+
+	stats := Statistics{
+		TotalRequests:    len(r.Requests),
+		StatusCodeCounts: make(map[int]int),
+		GRPCStatusCounts: make(map[string]int),
+		DNSRcodeCounts:   make(map[string]int),
+		URLCounts:        make(map[string]int),
+		ErrorClassCounts: make(map[ErrorClass]int),
+		TokenFetches:     groupStatistics(r.TokenFetches, urlOf),
+	}
+
+	if stats.TotalRequests == 0 {
+		return stats
+	}
+
+	durations := make([]time.Duration, 0, stats.TotalRequests)
+	var totalDuration time.Duration
+
+	for _, req := range r.Requests {
+		switch {
+		case req.Protocol == ProtocolGRPC:
+			stats.GRPCStatusCounts[GRPCCodeName(req.StatusCode)]++
+		case req.Protocol == ProtocolDNS:
+			if req.Error == "" || req.ErrorClass == ErrorClassDNSRcode {
+				stats.DNSRcodeCounts[DNSRcodeName(req.StatusCode)]++
+			}
+		case req.StatusCode != 0:
+			stats.StatusCodeCounts[req.StatusCode]++
+		}
+
+		if req.Error == "" {
+			stats.SuccessRequests++
+		} else {
+			stats.FailedRequests++
+			class := req.ErrorClass
+			if class == "" {
+				class = ErrorClassOther
+			}
+			stats.ErrorClassCounts[class]++
+			if stats.FirstErrorTime.IsZero() || req.Timestamp.Before(stats.FirstErrorTime) {
+				stats.FirstErrorTime = req.Timestamp
+			}
+			if req.Timestamp.After(stats.LastErrorTime) {
+				stats.LastErrorTime = req.Timestamp
+			}
+		}
+
+		if req.URL != "" {
+			stats.URLCounts[req.URL]++
+		}
+
+		durations = append(durations, req.Duration)
+		totalDuration += req.Duration
+	}
+
+	// Sort durations for percentile calculations
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	stats.TotalDuration = r.EndTime.Sub(r.StartTime)
+	stats.MinDuration = durations[0]
+	stats.MaxDuration = durations[len(durations)-1]
+	stats.AvgDuration = totalDuration / time.Duration(stats.TotalRequests)
+	stats.MedianDuration = durations[len(durations)/2]
+	stats.P95Duration = durations[int(float64(len(durations))*0.95)]
+	stats.P99Duration = durations[int(float64(len(durations))*0.99)]
+	stats.RequestsPerSec = float64(stats.TotalRequests) / stats.TotalDuration.Seconds()
+	stats.Phases = groupStatistics(r.Requests, phaseOf)
+	stats.Operations = groupStatistics(r.Requests, operationOf)
+	stats.TopErrors = summarizeErrors(r.Requests)
+
+	return stats
+}
+
+// Redact rewrites every user-visible string in the results through mask so
+// that secret values never reach a generated report.
+func (r *Results) Redact(mask func(string) string) {
+	for i := range r.URLs {
+		r.URLs[i] = mask(r.URLs[i])
+	}
+	for i := range r.Requests {
+		r.Requests[i].URL = mask(r.Requests[i].URL)
+		r.Requests[i].Error = mask(r.Requests[i].Error)
+	}
+	if r.Warmup != nil {
+		for i := range r.Warmup.Requests {
+			r.Warmup.Requests[i].URL = mask(r.Warmup.Requests[i].URL)
+			r.Warmup.Requests[i].Error = mask(r.Warmup.Requests[i].Error)
+		}
+	}
+	for i := range r.TokenFetches {
+		r.TokenFetches[i].URL = mask(r.TokenFetches[i].URL)
+		r.TokenFetches[i].Error = mask(r.TokenFetches[i].Error)
+	}
+	if a := r.Aggregate; a != nil {
+		urlCounts := make(map[string]int, len(a.URLCounts))
+		for url, count := range a.URLCounts {
+			urlCounts[mask(url)] += count
+		}
+		a.URLCounts = urlCounts
+		tokenFetches := make(map[string]*GroupAggregate, len(a.TokenFetches))
+		for url, g := range a.TokenFetches {
+			mergeGroups(tokenFetches, map[string]*GroupAggregate{mask(url): g})
+		}
+		a.TokenFetches = tokenFetches
+		for i := range a.Errors {
+			a.Errors[i].Message = mask(a.Errors[i].Message)
+			for j := range a.Errors[i].SampleURLs {
+				a.Errors[i].SampleURLs[j] = mask(a.Errors[i].SampleURLs[j])
+			}
+		}
+	}
+}
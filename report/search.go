@@ -0,0 +1,76 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// SearchResult is the outcome of a capacity search.
+type SearchResult struct {
+	Strategy string
+	// MaxPassingRPS is the highest step rate that met the SLO, 0 when no
+	// step did.
+	MaxPassingRPS int
+	Steps         []SearchStep
+}
+
+// SearchStep is one rate tried by a capacity search.
+type SearchStep struct {
+	TargetRPS   int
+	AchievedRPS float64
+	Requests    int
+	P99Duration time.Duration
+	// ErrorRate is the percentage of failed requests.
+	ErrorRate float64
+	Passed    bool
+	// Violations describe the SLO criteria the step missed.
+	Violations []string
+}
+
+// Chart dimensions of the search chart in the HTML report.
+const (
+	searchChartWidth  = 760
+	searchChartHeight = 260
+	searchChartMargin = 40
+)
+
+// searchChart draws the achieved rate of every step as a bar over its
+// target rate, green for passing and red for failing steps.
+func searchChart(s *SearchResult) template.HTML {
+	if s == nil || len(s.Steps) == 0 {
+		return ""
+	}
+	top := 0.0
+	for _, step := range s.Steps {
+		top = max(top, float64(step.TargetRPS), step.AchievedRPS)
+	}
+	plotW := float64(searchChartWidth - 2*searchChartMargin)
+	plotH := float64(searchChartHeight - 2*searchChartMargin)
+	slot := plotW / float64(len(s.Steps))
+	y := func(rps float64) float64 {
+		return float64(searchChartHeight-searchChartMargin) - rps/top*plotH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="11" font-family="sans-serif">`, searchChartWidth, searchChartHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`, searchChartMargin, searchChartHeight-searchChartMargin, searchChartWidth-searchChartMargin, searchChartHeight-searchChartMargin)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%.0f RPS</text>`, 2, searchChartMargin-8, top)
+	for i, step := range s.Steps {
+		x := float64(searchChartMargin) + float64(i)*slot
+		color := "#dc3545"
+		if step.Passed {
+			color = "#28a745"
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>target %d RPS, achieved %.1f RPS, p99 %s, errors %.2f%%</title></rect>`,
+			x+slot*0.15, y(step.AchievedRPS), slot*0.7, y(0)-y(step.AchievedRPS), color,
+			step.TargetRPS, step.AchievedRPS, step.P99Duration, step.ErrorRate)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333" stroke-dasharray="4 2"/>`,
+			x+slot*0.05, y(float64(step.TargetRPS)), x+slot*0.95, y(float64(step.TargetRPS)))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%d</text>`, x+slot/2, searchChartHeight-searchChartMargin+15, step.TargetRPS)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">target RPS (bars: achieved, dashes: target)</text>`, searchChartWidth/2, searchChartHeight-8)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
@@ -0,0 +1,16 @@
+package report
+
+// ProtocolSSE marks a RequestResult from an sse endpoint. Its Phase tells
+// which part of the stream the result measures; PhaseSession is the stream
+// lifetime.
+const ProtocolSSE = "sse"
+
+// Stream phases of SSE results.
+const (
+	// PhaseFirstEvent is the time from sending the request until the first
+	// event arrived.
+	PhaseFirstEvent = "first_event"
+	// PhaseEventGap is the time between two consecutive events of a
+	// stream.
+	PhaseEventGap = "event_gap"
+)
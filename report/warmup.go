@@ -0,0 +1,44 @@
+package report
+
+import (
+	"slices"
+	"time"
+)
+
+// Warmup holds the requests sent before measurement started. Like token
+// requests they are kept apart from Requests, so that connection setup and
+// cold caches on the target do not skew the statistics of the run.
+type Warmup struct {
+	// RPS is the rate of the weighted endpoints during the warm-up.
+	RPS       int
+	StartTime time.Time
+	EndTime   time.Time
+	// Requests are the warm-up requests, each tagged with Warmup.
+	Requests []RequestResult
+	// Included makes CalculateStatistics of the run count the warm-up
+	// requests together with the measured ones.
+	Included bool
+}
+
+// Statistics returns the statistics of the warm-up requests alone.
+func (w *Warmup) Statistics() Statistics {
+	r := Results{StartTime: w.StartTime, EndTime: w.EndTime, Requests: w.Requests}
+	return r.CalculateStatistics()
+}
+
+// withWarmup returns the results with the warm-up requests counted as
+// measured ones, starting at the start of the warm-up.
+func (r *Results) withWarmup() *Results {
+	w := r.Warmup
+	combined := *r
+	combined.Warmup = nil
+	combined.StartTime = w.StartTime
+	if r.Aggregate != nil {
+		combined.Aggregate = NewAggregate()
+		combined.Aggregate.Merge(r.Aggregate)
+		combined.Aggregate.Merge((&Results{Requests: w.Requests}).Summarize())
+	} else {
+		combined.Requests = append(slices.Clip(w.Requests), r.Requests...)
+	}
+	return &combined
+}
@@ -0,0 +1,18 @@
+package report
+
+// ProtocolWebSocket marks a RequestResult from a websocket endpoint. Its
+// Phase tells which part of the connection the result measures.
+const ProtocolWebSocket = "websocket"
+
+// Connection phases of WebSocket results.
+const (
+	// PhaseConnect is the opening handshake, from dialing until the
+	// server accepted the upgrade.
+	PhaseConnect = "connect"
+	// PhaseMessage is the round trip of one message, from sending it until
+	// the server echoed it back.
+	PhaseMessage = "message"
+	// PhaseSession is the whole connection. It fails when the connection
+	// dropped before the client closed it.
+	PhaseSession = "session"
+)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokens maps the access tokens handed out by /oauth/token to their expiry.
+var tokens sync.Map
+
+// handleToken implements the OAuth2 client credentials grant for any client
+// that authenticates with HTTP basic authentication. Tokens expire after
+// ?expires_in=S seconds (default 60); ?delay=MS delays the response.
+func handleToken(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	if v, err := strconv.Atoi(r.URL.Query().Get("delay")); err == nil && v > 0 {
+		time.Sleep(time.Duration(v) * time.Millisecond)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	id, _, ok := r.BasicAuth()
+	if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if !ok || id == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	expiresIn := 60
+	if v, err := strconv.Atoi(r.URL.Query().Get("expires_in")); err == nil && v > 0 {
+		expiresIn = v
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+	tokens.Store(token, time.Now().Add(time.Duration(expiresIn)*time.Second))
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   expiresIn,
+	})
+}
+
+// handleProtected answers 200 for requests with a bearer token from
+// /oauth/token that has not expired and 401 for everyone else.
+func handleProtected(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	expiry, ok := tokens.Load(token)
+	if !ok || time.Now().After(expiry.(time.Time)) {
+		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// handleGraphQL answers GraphQL requests without executing them: the
+// response data echoes the operation name and variables. Requests whose
+// operation name or query contains "fail" get an errors field with status
+// 200, like a real GraphQL server reporting a resolver error.
+func handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	var req struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "GraphQL requests must be POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		http.Error(w, "invalid GraphQL request", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if strings.Contains(strings.ToLower(req.OperationName+" "+req.Query), "fail") {
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":   nil,
+			"errors": []map[string]string{{"message": "requested failure"}},
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{
+			"operation": req.OperationName,
+			"variables": req.Variables,
+		},
+	})
+}
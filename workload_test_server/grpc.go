@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// echoFile describes the test service. It is built at runtime so that the
+// test server needs no generated code:
+//
+//	service Echo {
+//	  rpc Echo(EchoRequest) returns (EchoReply);                  // unary
+//	  rpc Stream(EchoRequest) returns (stream EchoReply);         // count replies
+//	  rpc Collect(stream EchoRequest) returns (EchoReply);        // joins messages
+//	  rpc Chat(stream EchoRequest) returns (stream EchoReply);    // echoes each message
+//	}
+//
+//	message EchoRequest { string message = 1; int32 count = 2; string fail = 3; }
+//	message EchoReply { string message = 1; int32 index = 2; }
+//
+// Setting fail to a status code name such as "UNAVAILABLE" makes Echo
+// return that status.
+var echoFile = &descriptorpb.FileDescriptorProto{
+	Name:    proto.String("meteorshower/test/echo.proto"),
+	Package: proto.String("meteorshower.test"),
+	Syntax:  proto.String("proto3"),
+	MessageType: []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("EchoRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				stringField("message", 1),
+				{Name: proto.String("count"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("count")},
+				stringField("fail", 3),
+			},
+		},
+		{
+			Name: proto.String("EchoReply"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				stringField("message", 1),
+				{Name: proto.String("index"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("index")},
+			},
+		},
+	},
+	Service: []*descriptorpb.ServiceDescriptorProto{{
+		Name: proto.String("Echo"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			rpcMethod("Echo", false, false),
+			rpcMethod("Stream", false, true),
+			rpcMethod("Collect", true, false),
+			rpcMethod("Chat", true, true),
+		},
+	}},
+}
+
+func stringField(name string, number int32) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		JsonName: proto.String(name),
+	}
+}
+
+func rpcMethod(name string, clientStreaming, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(".meteorshower.test.EchoRequest"),
+		OutputType:      proto.String(".meteorshower.test.EchoReply"),
+		ClientStreaming: proto.Bool(clientStreaming),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+}
+
+// startGRPC serves the Echo service, the standard health service and
+// server reflection on port.
+func startGRPC(port int) error {
+	fd, err := protodesc.NewFile(echoFile, protoregistry.GlobalFiles)
+	if err != nil {
+		return err
+	}
+	// Server reflection serves the files registered globally.
+	if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+		return err
+	}
+	service := fd.Services().Get(0)
+	request := fd.Messages().ByName("EchoRequest")
+	reply := fd.Messages().ByName("EchoReply")
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(echoServiceDesc(service, request, reply), nil)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	return nil
+}
+
+func echoServiceDesc(service protoreflect.ServiceDescriptor, request, reply protoreflect.MessageDescriptor) *grpc.ServiceDesc {
+	newReply := func(message string, index int) *dynamicpb.Message {
+		msg := dynamicpb.NewMessage(reply)
+		msg.Set(reply.Fields().ByName("message"), protoreflect.ValueOfString(message))
+		msg.Set(reply.Fields().ByName("index"), protoreflect.ValueOfInt32(int32(index)))
+		return msg
+	}
+	field := func(msg *dynamicpb.Message, name string) protoreflect.Value {
+		return msg.Get(request.Fields().ByName(protoreflect.Name(name)))
+	}
+
+	return &grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				atomic.AddInt64(&requestCount, 1)
+				in := dynamicpb.NewMessage(request)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if fail := field(in, "fail").String(); fail != "" {
+					for code := codes.OK; code <= codes.Unauthenticated; code++ {
+						if strings.EqualFold(strings.ReplaceAll(code.String(), "_", ""), strings.ReplaceAll(fail, "_", "")) {
+							return nil, status.Error(code, "requested failure")
+						}
+					}
+					return nil, status.Errorf(codes.InvalidArgument, "unknown status code %q", fail)
+				}
+				return newReply(field(in, "message").String(), 0), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Stream",
+				ServerStreams: true,
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					atomic.AddInt64(&requestCount, 1)
+					in := dynamicpb.NewMessage(request)
+					if err := stream.RecvMsg(in); err != nil {
+						return err
+					}
+					count := int(field(in, "count").Int())
+					if count <= 0 {
+						count = 1
+					}
+					for i := 0; i < count; i++ {
+						if err := stream.SendMsg(newReply(field(in, "message").String(), i)); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			},
+			{
+				StreamName:    "Collect",
+				ClientStreams: true,
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					atomic.AddInt64(&requestCount, 1)
+					var messages []string
+					for {
+						in := dynamicpb.NewMessage(request)
+						if err := stream.RecvMsg(in); err != nil {
+							if errors.Is(err, io.EOF) {
+								return stream.SendMsg(newReply(strings.Join(messages, " "), len(messages)))
+							}
+							return err
+						}
+						messages = append(messages, field(in, "message").String())
+					}
+				},
+			},
+			{
+				StreamName:    "Chat",
+				ClientStreams: true,
+				ServerStreams: true,
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					atomic.AddInt64(&requestCount, 1)
+					for i := 0; ; i++ {
+						in := dynamicpb.NewMessage(request)
+						if err := stream.RecvMsg(in); err != nil {
+							if errors.Is(err, io.EOF) {
+								return nil
+							}
+							return err
+						}
+						if err := stream.SendMsg(newReply(field(in, "message").String(), i)); err != nil {
+							return err
+						}
+					}
+				},
+			},
+		},
+		Metadata: echoFile.GetName(),
+	}
+}
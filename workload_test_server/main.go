@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/net/websocket"
 )
 
 var (
@@ -16,6 +18,10 @@ var (
 	delay        = flag.Int("delay", 10, "Response delay in milliseconds")
 	errorRate    = flag.Float64("error-rate", 0.0, "Error rate (0.0 to 1.0)")
 	randomDelay  = flag.Bool("random-delay", false, "Add random delay variation")
+	grpcPort     = flag.Int("grpc-port", 9090, "Port for the gRPC test service (0 disables it)")
+	tcpPort      = flag.Int("tcp-port", 9091, "Port for the TCP line echo service (0 disables it)")
+	udpPort      = flag.Int("udp-port", 9092, "Port for the UDP echo service (0 disables it)")
+	dnsPort      = flag.Int("dns-port", 9053, "Port for the DNS test service over UDP (0 disables it)")
 	requestCount int64
 )
 
@@ -38,6 +44,13 @@ func main() {
 	http.HandleFunc("/stats", handleStats)
 	http.HandleFunc("/slow", handleSlow)
 	http.HandleFunc("/error", handleError)
+	http.Handle("/ws", websocket.Handler(handleWebSocket))
+	http.HandleFunc("/sse", handleSSE)
+	http.HandleFunc("/graphql", handleGraphQL)
+	http.HandleFunc("/login", handleLogin)
+	http.HandleFunc("/session", handleSession)
+	http.HandleFunc("/oauth/token", handleToken)
+	http.HandleFunc("/protected", handleProtected)
 
 	addr := fmt.Sprintf(":%d", *port)
 	log.Printf("Starting test server on %s", addr)
@@ -51,6 +64,42 @@ func main() {
 	log.Printf("  GET /stats    - Server statistics")
 	log.Printf("  GET /slow     - Slow endpoint (500ms delay)")
 	log.Printf("  GET /error    - Always returns 500 error")
+	log.Printf("  GET /ws       - WebSocket echo; ?close_after=N drops the connection after N messages")
+	log.Printf("  GET /sse      - Server-Sent Events; ?events=N (0 = endless) and ?interval=MS")
+	log.Printf("  POST /graphql - GraphQL echo; operations mentioning fail return errors")
+	log.Printf("  GET /login    - Starts a session and sets the session cookie")
+	log.Printf("  GET /session  - 200 with a session cookie from /login, 401 without")
+	log.Printf("  POST /oauth/token - OAuth2 client credentials; ?expires_in=S and ?delay=MS")
+	log.Printf("  GET /protected    - 200 with an unexpired bearer token from /oauth/token, 401 without")
+	if *grpcPort > 0 {
+		if err := startGRPC(*grpcPort); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("\ngRPC on :%d (with server reflection):", *grpcPort)
+		log.Printf("  meteorshower.test.Echo/Echo     - Unary echo; set \"fail\" to a status code name to fail")
+		log.Printf("  meteorshower.test.Echo/Stream   - Server streaming, sends \"count\" replies")
+		log.Printf("  meteorshower.test.Echo/Collect  - Client streaming, joins the messages")
+		log.Printf("  meteorshower.test.Echo/Chat     - Bidirectional streaming echo")
+		log.Printf("  grpc.health.v1.Health/Check     - Standard health check")
+	}
+	if *tcpPort > 0 {
+		if err := startTCP(*tcpPort); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("\nTCP on :%d - answers every line with \"OK <line>\"", *tcpPort)
+	}
+	if *udpPort > 0 {
+		if err := startUDP(*udpPort); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("UDP on :%d - echoes every datagram", *udpPort)
+	}
+	if *dnsPort > 0 {
+		if err := startDNS(*dnsPort); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("DNS on :%d (UDP) - A records resolve to 127.0.0.1, names starting with nx get NXDOMAIN", *dnsPort)
+	}
 	log.Printf("\n")
 
 	if err := http.ListenAndServe(addr, nil); err != nil {
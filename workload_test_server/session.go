@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// sessions holds the ids handed out by /login.
+var sessions sync.Map
+
+// handleLogin starts a session and sets its id as the session cookie.
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	var b [16]byte
+	rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+	sessions.Store(id, true)
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: id, Path: "/", HttpOnly: true})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "logged in"})
+}
+
+// handleSession answers 200 for requests with the cookie of a session
+// started by /login and 401 for everyone else.
+func handleSession(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	c, err := r.Cookie("session")
+	if err != nil {
+		http.Error(w, "no session cookie", http.StatusUnauthorized)
+		return
+	}
+	if _, ok := sessions.Load(c.Value); !ok {
+		http.Error(w, "unknown session", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "session": c.Value})
+}
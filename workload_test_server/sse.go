@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// handleSSE streams Server-Sent Events. ?events=N sets the number of events
+// before the server ends the stream (default 10, 0 streams until the client
+// disconnects) and ?interval=MS the time between events (default 100). A
+// comment is sent before every event as a heartbeat.
+func handleSSE(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+
+	events := 10
+	if v := r.URL.Query().Get("events"); v != "" {
+		events, _ = strconv.Atoi(v)
+	}
+	interval := 100 * time.Millisecond
+	if v, err := strconv.Atoi(r.URL.Query().Get("interval")); err == nil && v > 0 {
+		interval = time.Duration(v) * time.Millisecond
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 1; events == 0 || i <= events; i++ {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		fmt.Fprintf(w, ": heartbeat\n\nid: %d\nevent: tick\ndata: {\"n\":%d,\"time\":%q}\n\n", i, i, time.Now().Format(time.RFC3339Nano))
+		flusher.Flush()
+	}
+}
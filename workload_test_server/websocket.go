@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"golang.org/x/net/websocket"
+)
+
+// handleWebSocket echoes every text message back on the connection. With
+// ?close_after=N the server drops the connection after echoing N messages,
+// to simulate connection drops.
+func handleWebSocket(ws *websocket.Conn) {
+	atomic.AddInt64(&requestCount, 1)
+	defer ws.Close()
+
+	closeAfter, _ := strconv.Atoi(ws.Request().URL.Query().Get("close_after"))
+	for n := 1; ; n++ {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			return
+		}
+		if err := websocket.Message.Send(ws, msg); err != nil {
+			log.Printf("websocket: %v", err)
+			return
+		}
+		if closeAfter > 0 && n >= closeAfter {
+			return
+		}
+	}
+}